@@ -2,6 +2,9 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
+	"os"
 
 	"gbu-scanner/internal/app"
 
@@ -11,15 +14,88 @@ import (
 	"github.com/pkg/errors"
 )
 
+// usage is help message printed on unknown command.
+const usage = `Usage: gbu-scanner [command] [flags]
+
+Commands:
+  scan [--once]  scan blogs and publish new posts (default command),
+                 with --once scans each blog once and exits
+  list [--json]  print published posts from storage
+  fetch [--json] print posts blogs' parsers currently see, storage and broker are not touched
+  diff [--json]  print events which would be published on next scan
+  replay [--since DATE] [--until DATE] [--author AUTHOR] [--url REGEXP] [--source BLOG] [--rate N]
+                 republish published posts with "replay" header, N posts per second at most
+  topology diff [--json]
+                 print differences between RABBIT_TOPOLOGY_FILE and broker's topology
+`
+
 func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	log := logger.NewLogrus()
 
 	graceful.OnShutdown(cancel)
 
-	err := app.Run(ctx, log)
+	command, args := "scan", os.Args[1:]
+	if len(args) != 0 {
+		command, args = args[0], args[1:]
+	}
+
+	// Stdout is for commands' output.
+	if command != "scan" {
+		log.Out = os.Stderr
+	}
+
+	err := run(ctx, command, args, log)
 	if err != nil {
-		err = errors.Wrap(err, "error running app")
+		err = errors.Wrapf(err, "error running %s command", command)
 		log.Fatal(err)
 	}
 }
+
+// run runs command with it's args.
+func run(ctx context.Context, command string, args []string, log logger.Logger) error {
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+
+	switch command {
+	case "scan":
+		once := flags.Bool("once", false, "scan each blog once and exit")
+		_ = flags.Parse(args) // Exits on error.
+		if *once {
+			return app.ScanOnce(ctx, log)
+		}
+		return app.Run(ctx, log)
+	case "list":
+		asJSON := flags.Bool("json", false, "print posts as JSON")
+		_ = flags.Parse(args)
+		return app.List(ctx, os.Stdout, *asJSON, log)
+	case "fetch":
+		asJSON := flags.Bool("json", false, "print posts as JSON")
+		_ = flags.Parse(args)
+		return app.Fetch(ctx, os.Stdout, *asJSON, log)
+	case "diff":
+		asJSON := flags.Bool("json", false, "print events as JSON")
+		_ = flags.Parse(args)
+		return app.Diff(ctx, os.Stdout, *asJSON, log)
+	case "replay":
+		var options app.ReplayOptions
+		flags.StringVar(&options.Since, "since", "", "replay posts since date (2006-01-02 or RFC3339)")
+		flags.StringVar(&options.Until, "until", "", "replay posts until date (2006-01-02 or RFC3339)")
+		flags.StringVar(&options.Author, "author", "", "replay posts which author contains substring")
+		flags.StringVar(&options.URLPattern, "url", "", "replay posts which url matches regular expression")
+		flags.StringVar(&options.Source, "source", "", "replay posts of blog")
+		flags.Float64Var(&options.Rate, "rate", 1, "max count of published posts per second")
+		_ = flags.Parse(args)
+		return app.Replay(ctx, options, log)
+	case "topology":
+		if len(args) == 0 || args[0] != "diff" {
+			fmt.Fprint(os.Stderr, usage)
+			return errors.New("unknown topology subcommand, only diff is available")
+		}
+		asJSON := flags.Bool("json", false, "print differences as JSON")
+		_ = flags.Parse(args[1:])
+		return app.TopologyDiff(ctx, os.Stdout, *asJSON, log)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return errors.Errorf("unknown command %q", command)
+	}
+}
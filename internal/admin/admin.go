@@ -0,0 +1,138 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"gbu-scanner/pkg/logger"
+
+	"github.com/pkg/errors"
+)
+
+// Check is readiness check of one dependency, it returns error if dependency is not ready.
+type Check func(ctx context.Context) error
+
+// namedCheck is Check with name to show in /readyz response.
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Server is admin HTTP server with health and readiness endpoints.
+type Server struct {
+	addr   string
+	mux    *http.ServeMux
+	checks []namedCheck
+	log    logger.Logger
+
+	mu *sync.RWMutex // Protects checks.
+}
+
+// New returns admin server listening on addr (for example ":8080").
+// Server starts listening only in Run method.
+func New(addr string, log logger.Logger) *Server {
+	s := &Server{
+		addr:   addr,
+		mux:    http.NewServeMux(),
+		checks: nil,
+		log:    log,
+
+		mu: &sync.RWMutex{},
+	}
+
+	s.mux.HandleFunc("/healthz", s.handleHealth)
+	s.mux.HandleFunc("/readyz", s.handleReady)
+
+	return s
+}
+
+// AddCheck registers readiness check. /readyz responds with OK
+// status only if all registered checks pass.
+func (s *Server) AddCheck(name string, check Check) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.checks = append(s.checks, namedCheck{name: name, check: check})
+}
+
+// Handle registers additional handler for pattern.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Run is a blocking method until context cancelled, it serves admin's endpoints.
+// When context is closed, server is gracefully shut down.
+// Run returns nil-error if server was shut down because of closed context.
+func (s *Server) Run(ctx context.Context) error {
+	server := &http.Server{
+		Addr:              s.addr,
+		Handler:           s.mux,
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServe()
+	}()
+
+	s.log.Infof("admin server listening on %s", s.addr)
+
+	select {
+	case err := <-errs:
+		return errors.Wrap(err, "listen and serve")
+	case <-ctx.Done():
+	}
+
+	// ctx is already closed, so new one is used to wait active requests.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err := server.Shutdown(shutdownCtx)
+	if err != nil {
+		return errors.Wrap(err, "shutdown server")
+	}
+
+	s.log.Info("admin server finished")
+
+	return nil
+}
+
+// handleHealth responds OK while process is alive.
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "ok")
+}
+
+// handleReady runs all readiness checks and responds with OK status if all of them pass,
+// with ServiceUnavailable otherwise. Response body contains result of each check.
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	checks := s.checks
+	s.mu.RUnlock()
+
+	report := &strings.Builder{}
+	isReady := true
+
+	for _, c := range checks {
+		ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+		err := c.check(ctx)
+		cancel()
+
+		if err != nil {
+			isReady = false
+			fmt.Fprintf(report, "%s: %s\n", c.name, err)
+			continue
+		}
+
+		fmt.Fprintf(report, "%s: ok\n", c.name)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if !isReady {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	fmt.Fprint(w, report.String())
+}
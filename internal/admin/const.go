@@ -0,0 +1,12 @@
+package admin
+
+import "time"
+
+// checkTimeout is timeout of each readiness check.
+const checkTimeout = 5 * time.Second
+
+// shutdownTimeout is duration how long server waits for active requests during shutdown.
+const shutdownTimeout = 5 * time.Second
+
+// readHeaderTimeout is http server's timeout for reading request's headers.
+const readHeaderTimeout = 5 * time.Second
@@ -0,0 +1,4 @@
+// Package admin provides embedded HTTP server for service's administration:
+// liveness (/healthz) and readiness (/readyz) endpoints for orchestrator's probes
+// and any additional handlers (like /metrics) registered with Server.Handle.
+package admin
@@ -0,0 +1,92 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"gbu-scanner/internal/admin"
+	"gbu-scanner/internal/leader"
+	"gbu-scanner/internal/metrics"
+	"gbu-scanner/internal/scanner"
+	"gbu-scanner/internal/webhook"
+
+	"gbu-scanner/pkg/logger"
+	"gbu-scanner/pkg/wrappers/mongo"
+
+	"github.com/pkg/errors"
+)
+
+// readinessChecker is implemented by dependencies which can report their readiness.
+type readinessChecker interface {
+	// Ready returns error if dependency is not ready.
+	Ready(ctx context.Context) error
+}
+
+// makeAdminServer makes admin server with metrics and readiness checks of mongo, publisher and scanner.
+// With webhook publisher endpoints' delivery statuses are available at /webhooks.
+// Scanner is considered ready if every blog was scanned successfully during last AdminReadyMaxScanAge
+// (or 3 blog's scan intervals), so it should be set if blogs are scanned with cron or quiet hours.
+// With leader election scanner of follower is always ready, as only leader scans. Elector can be nil.
+func makeAdminServer(
+	cfg appConfig,
+	mongo *mongo.Client,
+	deps dependencies,
+	scanner *scanner.Scanner,
+	elector *leader.Elector,
+	log logger.Logger,
+) (*admin.Server, error) {
+	sourceConfigs, err := cfg.blogSources()
+	if err != nil {
+		return nil, errors.Wrap(err, "get blog sources")
+	}
+
+	server := admin.New(cfg.AdminAddr, log)
+	server.Handle("/metrics", metrics.Handler())
+
+	server.AddCheck("mongo", func(ctx context.Context) error {
+		return mongo.Ping(ctx, nil)
+	})
+
+	if checker, ok := deps.publisher.(readinessChecker); ok {
+		server.AddCheck("publisher", checker.Ready)
+	}
+
+	if webhooks, ok := deps.publisher.(*webhook.Publisher); ok {
+		server.Handle("/webhooks", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(webhooks.Statuses())
+		}))
+	}
+
+	intervals := make(map[string]time.Duration, len(sourceConfigs))
+	for _, sourceConfig := range sourceConfigs {
+		intervals[sourceConfig.Name] = time.Duration(sourceConfig.ScanInterval) * time.Second
+	}
+
+	server.AddCheck("scanner", func(ctx context.Context) error {
+		if elector != nil && !elector.IsLeader() {
+			return nil
+		}
+
+		for name, lastSuccess := range scanner.LastSuccess() {
+			maxAge := time.Duration(cfg.AdminReadyMaxScanAge) * time.Second
+			if maxAge == 0 {
+				maxAge = defaultReadyMaxScanAgeIntervals * intervals[name]
+			}
+
+			if lastSuccess.IsZero() {
+				return errors.Errorf("no successful scans of %q yet", name)
+			}
+
+			if age := time.Since(lastSuccess); age > maxAge {
+				return errors.Errorf("last successful scan of %q was %s ago", name, age.Round(time.Second))
+			}
+		}
+
+		return nil
+	})
+
+	return server, nil
+}
@@ -4,12 +4,14 @@ import (
 	"context"
 	"time"
 
+	"gbu-scanner/internal/admin"
+	"gbu-scanner/internal/leader"
 	"gbu-scanner/internal/scanner"
 
-	"gbu-scanner/pkg/config"
 	"gbu-scanner/pkg/logger"
 
 	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
 )
 
 // Run runs app. If returned error is not nil, program exited
@@ -18,38 +20,84 @@ func Run(ctx context.Context, log logger.Logger) error {
 	log.Info("starting app")
 
 	// Getting configuration.
-	var cfg appConfig
-	err := config.Parse(&cfg)
+	cfg, err := loadConfig(log)
 	if err != nil {
-		return errors.Wrap(err, "parse config")
+		return errors.Wrap(err, "load config")
+	}
+
+	options, err := cfg.scannerOptions()
+	if err != nil {
+		return errors.Wrap(err, "make scanner options")
 	}
-	cfg.setDefaults(log)
 
 	// Getting required connections/clients.
 	mongo, err := makeConnections(ctx, cfg)
 	if err != nil {
 		return errors.Wrap(err, "make connections")
 	}
-	defer func() {
-		err := mongo.Disconnect(ctx) // Disconnects without error if context closed.
-		if err != nil {
-			log.Error(errors.Wrap(err, "can't disconnect mongo client"))
-		}
-	}()
+	defer disconnect(ctx, mongo, log)
 
-	// Making dependencies for scanner.
-	blog, publisher, posts, err := makeDependencies(ctx, cfg, mongo, log)
+	// Making dependencies for scanner and relay.
+	deps, err := makeDependencies(ctx, cfg, mongo, log)
 	if err != nil {
 		return errors.Wrap(err, "construct dependencies")
 	}
 
-	// Constructing and launching scanner.
-	blogScanInterval := time.Duration(cfg.BlogScanInterval) * time.Second
-	scanner := scanner.New(blog, publisher, posts, blogScanInterval, log)
+	// Constructing scanner and relay.
+	relayInterval := time.Duration(cfg.OutboxRelayInterval) * time.Second
+	relay := scanner.NewRelay(deps.outbox, deps.publisher, relayInterval, cfg.OutboxBatchSize, log)
+	scanner := scanner.New(deps.sources, deps.posts, deps.outbox, options, log)
+
+	// Launching scanner and relay. If one of them fails, other one is stopped.
+	work := func(ctx context.Context) error {
+		g, gCtx := errgroup.WithContext(ctx)
+		g.Go(func() error {
+			return errors.Wrap(scanner.Scan(gCtx), "scanning")
+		})
+		g.Go(func() error {
+			return errors.Wrap(relay.Run(gCtx), "relaying")
+		})
+		return g.Wait()
+	}
+
+	// With leader election scanner and relay work only while replica is leader.
+	// Dry run replica doesn't compete for leadership as it doesn't publish anything.
+	var elector *leader.Elector
+	if cfg.LeaderElection && cfg.DryRun {
+		log.Warn("dry run: leader election is disabled")
+	}
+	if cfg.LeaderElection && !cfg.DryRun {
+		elector, err = makeElector(cfg, mongo, log)
+		if err != nil {
+			return errors.Wrap(err, "make leader elector")
+		}
+	}
+
+	var adminServer *admin.Server
+	if cfg.AdminAddr != "" {
+		adminServer, err = makeAdminServer(cfg, mongo, deps, scanner, elector, log)
+		if err != nil {
+			return errors.Wrap(err, "make admin server")
+		}
+	}
+
+	g, gCtx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		if elector != nil {
+			return errors.Wrap(elector.Run(gCtx, work), "leading")
+		}
+		return work(gCtx)
+	})
+
+	if adminServer != nil {
+		g.Go(func() error {
+			return errors.Wrap(adminServer.Run(gCtx), "serving admin")
+		})
+	}
 
-	err = scanner.Scan(ctx)
+	err = g.Wait()
 	if err != nil {
-		return errors.Wrap(err, "scanning")
+		return err
 	}
 
 	log.Info("app finished")
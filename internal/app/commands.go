@@ -0,0 +1,379 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"gbu-scanner/internal/dryrun"
+	"gbu-scanner/internal/entity"
+	"gbu-scanner/internal/scanner"
+	"gbu-scanner/internal/topology"
+
+	"gbu-scanner/pkg/logger"
+
+	"github.com/pkg/errors"
+)
+
+// dateFormat is format of posts' dates in tables.
+const dateFormat = "2006-01-02"
+
+// managementTimeout is timeout of requests to rabbit's management API.
+const managementTimeout = 10 * time.Second
+
+// ScanOnce scans each blog once, publishes pending posts from outbox and returns.
+// It's for running scanner by cron, leader election is not used.
+func ScanOnce(ctx context.Context, log logger.Logger) error {
+	cfg, err := loadConfig(log)
+	if err != nil {
+		return errors.Wrap(err, "load config")
+	}
+
+	options, err := cfg.scannerOptions()
+	if err != nil {
+		return errors.Wrap(err, "make scanner options")
+	}
+
+	mongo, err := makeConnections(ctx, cfg)
+	if err != nil {
+		return errors.Wrap(err, "make connections")
+	}
+	defer disconnect(ctx, mongo, log)
+
+	deps, err := makeDependencies(ctx, cfg, mongo, log)
+	if err != nil {
+		return errors.Wrap(err, "construct dependencies")
+	}
+
+	relay := scanner.NewRelay(deps.outbox, deps.publisher, 0, cfg.OutboxBatchSize, log)
+	scanner := scanner.New(deps.sources, deps.posts, deps.outbox, options, log)
+
+	// Posts enqueued before failed blog's scan are published anyway.
+	scanErr := scanner.ScanOnce(ctx)
+
+	err = relay.Drain(ctx)
+	if err != nil {
+		return errors.Wrap(err, "publish pending posts")
+	}
+
+	return errors.Wrap(scanErr, "scan")
+}
+
+// List writes published posts from storage to w, from newest to oldest.
+func List(ctx context.Context, w io.Writer, asJSON bool, log logger.Logger) error {
+	cfg, err := loadConfig(log)
+	if err != nil {
+		return errors.Wrap(err, "load config")
+	}
+
+	mongo, err := makeConnections(ctx, cfg)
+	if err != nil {
+		return errors.Wrap(err, "make connections")
+	}
+	defer disconnect(ctx, mongo, log)
+
+	posts, err := makeReadOnlyPosts(cfg, mongo, log)
+	if err != nil {
+		return errors.Wrap(err, "make posts")
+	}
+
+	publishedPosts, err := posts.GetAll(ctx)
+	if err != nil {
+		return errors.Wrap(err, "get published posts")
+	}
+
+	sort.SliceStable(publishedPosts, func(i, j int) bool {
+		return publishedPosts[i].Date.After(publishedPosts[j].Date)
+	})
+
+	return writePosts(w, publishedPosts, asJSON)
+}
+
+// Fetch writes posts which blogs' parsers currently see to w without touching storage or broker.
+// HTTP cache is not used, so posts are always fetched.
+func Fetch(ctx context.Context, w io.Writer, asJSON bool, log logger.Logger) error {
+	cfg, err := loadConfig(log)
+	if err != nil {
+		return errors.Wrap(err, "load config")
+	}
+
+	sources, err := makeSources(ctx, cfg, nil, log)
+	if err != nil {
+		return errors.Wrap(err, "make sources")
+	}
+
+	var posts []entity.Post
+	for _, source := range sources {
+		sourcePosts, err := source.Blog.GetPosts(ctx)
+		if err != nil {
+			return errors.Wrapf(err, "get posts of %q", source.Name)
+		}
+
+		for i := range sourcePosts {
+			sourcePosts[i].Source = source.Name
+		}
+
+		posts = append(posts, sourcePosts...)
+	}
+
+	return writePosts(w, posts, asJSON)
+}
+
+// Diff writes events which would be published on next scan to w without writing to storage or publishing.
+func Diff(ctx context.Context, w io.Writer, asJSON bool, log logger.Logger) error {
+	cfg, err := loadConfig(log)
+	if err != nil {
+		return errors.Wrap(err, "load config")
+	}
+
+	options, err := cfg.scannerOptions()
+	if err != nil {
+		return errors.Wrap(err, "make scanner options")
+	}
+
+	mongo, err := makeConnections(ctx, cfg)
+	if err != nil {
+		return errors.Wrap(err, "make connections")
+	}
+	defer disconnect(ctx, mongo, log)
+
+	posts, err := makeReadOnlyPosts(cfg, mongo, log)
+	if err != nil {
+		return errors.Wrap(err, "make posts")
+	}
+
+	// Without mongo sources are made without http cache, so blogs are always fetched.
+	sources, err := makeSources(ctx, cfg, nil, log)
+	if err != nil {
+		return errors.Wrap(err, "make sources")
+	}
+
+	scanner := scanner.New(sources, posts, nil, options, log)
+
+	events, err := scanner.Preview(ctx)
+	if err != nil {
+		return errors.Wrap(err, "preview events")
+	}
+
+	return writeEvents(w, events, asJSON)
+}
+
+// writePosts writes posts to w as JSON or as table.
+func writePosts(w io.Writer, posts []entity.Post, asJSON bool) error {
+	if asJSON {
+		return writeJSON(w, posts)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DATE\tSOURCE\tREVISION\tTITLE\tURL")
+	for _, p := range posts {
+		title := p.Title
+		if p.Removed {
+			title += " (removed)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", p.Date.Format(dateFormat), p.Source, p.Revision, title, p.URL)
+	}
+
+	return errors.Wrap(tw.Flush(), "write table")
+}
+
+// writeEvents writes events to w as JSON or as table with diffs of updated posts.
+func writeEvents(w io.Writer, events []entity.Event, asJSON bool) error {
+	if asJSON {
+		return writeJSON(w, events)
+	}
+
+	if len(events) == 0 {
+		_, err := fmt.Fprintln(w, "nothing to publish")
+		return errors.Wrap(err, "write")
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "EVENT\tSOURCE\tREVISION\tTITLE\tURL")
+	for _, event := range events {
+		p := event.Post
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", event.Type, p.Source, p.Revision, p.Title, p.URL)
+		for _, change := range event.Diff {
+			fmt.Fprintf(tw, "\t\t\t  %s: %q -> %q\t\n", change.Field, change.Old, change.New)
+		}
+	}
+
+	return errors.Wrap(tw.Flush(), "write table")
+}
+
+// writeJSON writes v to w as indented JSON.
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return errors.Wrap(encoder.Encode(v), "encode json")
+}
+
+// ReplayOptions are options of Replay command.
+type ReplayOptions struct {
+	// Since and Until limit posts' dates, formatted as "2006-01-02" or RFC3339. Optional.
+	Since, Until string
+	// Author is case-insensitive substring of posts' author. Optional.
+	Author string
+	// URLPattern is regular expression for posts' urls. Optional.
+	URLPattern string
+	// Source is name of posts' blog. Optional.
+	Source string
+	// Rate is max count of published posts per second.
+	Rate float64
+}
+
+// Replay republishes published posts selected by options from storage to broker with "replay" header,
+// so new consumers can get history. Replayed posts are logged only if DRY_RUN is enabled.
+func Replay(ctx context.Context, options ReplayOptions, log logger.Logger) error {
+	filter, err := options.filter()
+	if err != nil {
+		return errors.Wrap(err, "parse options")
+	}
+
+	if options.Rate <= 0 {
+		return errors.Errorf("rate must be positive, got %v", options.Rate)
+	}
+	interval := time.Duration(float64(time.Second) / options.Rate)
+
+	cfg, err := loadConfig(log)
+	if err != nil {
+		return errors.Wrap(err, "load config")
+	}
+
+	mongo, err := makeConnections(ctx, cfg)
+	if err != nil {
+		return errors.Wrap(err, "make connections")
+	}
+	defer disconnect(ctx, mongo, log)
+
+	posts, err := makeReadOnlyPosts(cfg, mongo, log)
+	if err != nil {
+		return errors.Wrap(err, "make posts")
+	}
+
+	var publisher scanner.Publisher = dryrun.NewPublisher(log)
+	if !cfg.DryRun {
+		publisher, err = makePublisher(ctx, cfg, log)
+		if err != nil {
+			return errors.Wrap(err, "make publisher")
+		}
+	}
+
+	replayed, err := scanner.NewReplayer(posts, publisher, log).Replay(ctx, filter, interval)
+	if err != nil {
+		return errors.Wrapf(err, "replay (%d posts replayed)", replayed)
+	}
+
+	log.Infof("%d posts replayed", replayed)
+
+	return nil
+}
+
+// filter parses options to scanner.ReplayFilter.
+func (o ReplayOptions) filter() (scanner.ReplayFilter, error) {
+	filter := scanner.ReplayFilter{
+		Author: o.Author,
+		Source: o.Source,
+	}
+
+	var err error
+	if o.Since != "" {
+		filter.Since, err = parseDate(o.Since)
+		if err != nil {
+			return scanner.ReplayFilter{}, errors.Wrap(err, "parse since")
+		}
+	}
+
+	if o.Until != "" {
+		filter.Until, err = parseDate(o.Until)
+		if err != nil {
+			return scanner.ReplayFilter{}, errors.Wrap(err, "parse until")
+		}
+
+		// Date without time includes whole day.
+		if len(o.Until) == len(dateFormat) {
+			filter.Until = filter.Until.Add(24*time.Hour - time.Nanosecond)
+		}
+	}
+
+	if o.URLPattern != "" {
+		filter.URLPattern, err = regexp.Compile(o.URLPattern)
+		if err != nil {
+			return scanner.ReplayFilter{}, errors.Wrap(err, "compile url pattern")
+		}
+	}
+
+	return filter, nil
+}
+
+// parseDate parses date formatted as dateFormat or RFC3339.
+func parseDate(s string) (time.Time, error) {
+	t, err := time.Parse(dateFormat, s)
+	if err == nil {
+		return t, nil
+	}
+
+	t, err = time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, errors.Errorf("date %q is neither in %s nor in RFC3339 format", s, dateFormat)
+	}
+
+	return t, nil
+}
+
+// TopologyDiff writes differences between topology declared in RABBIT_TOPOLOGY_FILE
+// and broker's actual topology (got with management API) to w.
+func TopologyDiff(ctx context.Context, w io.Writer, asJSON bool, log logger.Logger) error {
+	cfg, err := loadConfig(log)
+	if err != nil {
+		return errors.Wrap(err, "load config")
+	}
+
+	if cfg.RabbitTopologyFile == "" {
+		return errors.New("RABBIT_TOPOLOGY_FILE is not set")
+	}
+
+	if cfg.RabbitManagementURL == "" {
+		return errors.New("RABBIT_MANAGEMENT_URL is not set")
+	}
+
+	declared, err := topology.Load(cfg.RabbitTopologyFile)
+	if err != nil {
+		return errors.Wrap(err, "load topology")
+	}
+
+	client := &http.Client{Timeout: managementTimeout}
+	management := topology.NewManagementClient(cfg.RabbitManagementURL, cfg.RabbitUser, cfg.RabbitPass, cfg.RabbitVhost, client)
+
+	actual, err := management.Get(ctx)
+	if err != nil {
+		return errors.Wrap(err, "get broker's topology")
+	}
+
+	changes := topology.Diff(declared, actual)
+
+	if asJSON {
+		return writeJSON(w, changes)
+	}
+
+	if len(changes) == 0 {
+		_, err = fmt.Fprintln(w, "topology is up to date")
+		return errors.Wrap(err, "write")
+	}
+
+	for _, change := range changes {
+		fmt.Fprintf(w, "%s: %s\n", change.Kind, change.Entity)
+		for _, detail := range change.Details {
+			fmt.Fprintf(w, "  %s\n", detail)
+		}
+	}
+
+	return nil
+}
@@ -1,6 +1,72 @@
 package app
 
-import "gbu-scanner/pkg/logger"
+import (
+	"net/http"
+	"strings"
+	"time"
+	_ "time/tzdata" // Docker image has no timezones database required for BLOG_SCAN_TIMEZONE.
+
+	"gbu-scanner/internal/publisher"
+	"gbu-scanner/internal/scanner"
+
+	"gbu-scanner/pkg/config"
+	"gbu-scanner/pkg/logger"
+
+	"github.com/pkg/errors"
+)
+
+// Available values for appConfig.BlogSource and blogSourceConfig.Parser.
+const (
+	// blogSourceHTML makes scanner scrape posts from blog's HTML page (go.dev/blog/all).
+	blogSourceHTML = "html"
+	// blogSourceAtom makes scanner get posts from blog's Atom feed (go.dev/blog/feed.atom).
+	blogSourceAtom = "atom"
+)
+
+// Available values for appConfig.MongoStorageMode.
+const (
+	// mongoStorageTransactions stores all posts in one document and uses
+	// multi-document transactions (requires replica set).
+	mongoStorageTransactions = "transactions"
+	// mongoStorageStandalone stores one document per post and
+	// doesn't use transactions (works with standalone instance).
+	mongoStorageStandalone = "standalone"
+)
+
+// Available values for appConfig.Publisher.
+const (
+	// publisherRabbit publishes events to rabbitmq's exchange.
+	publisherRabbit = "rabbit"
+	// publisherWebhook POSTs events to HTTP endpoints (WEBHOOK_URLS).
+	publisherWebhook = "webhook"
+)
+
+// firstRunSinceLayout is layout of appConfig.FirstRunSince.
+const firstRunSinceLayout = "2006-01-02"
+
+// leaderLease is name of lease document of scanning replicas' leader.
+const leaderLease = "scanner"
+
+// Defaults for optional config vars.
+const (
+	defaultOutboxRelayInterval     = 5 // seconds
+	defaultOutboxBatchSize         = 100
+	defaultRabbitConfirmTimeout    = 10  // seconds
+	defaultRabbitReconnectMaxDelay = 60  // seconds
+	defaultRabbitSpoolMaxSize      = 100 // megabytes
+	defaultLeaderLeaseTTL          = 30  // seconds
+	defaultBlogRetryMaxAttempts    = 3
+	defaultBlogRetryBaseDelay      = 1  // seconds
+	defaultBlogRetryMaxDelay       = 30 // seconds
+	defaultBlogRetryJitter         = 0.2
+	defaultWebhookTimeout          = 10 // seconds
+	defaultWebhookMaxAttempts      = 3
+	defaultWebhookRetryBaseDelay   = 1  // seconds
+	defaultWebhookRetryMaxDelay    = 30 // seconds
+	// defaultReadyMaxScanAgeIntervals is count of source's scan intervals after last successful
+	// scan when scanner is considered not ready if AdminReadyMaxScanAge is empty.
+	defaultReadyMaxScanAgeIntervals = 3
+)
 
 // appConfig is struct for parsing ENV configuration.
 type appConfig struct {
@@ -8,13 +74,50 @@ type appConfig struct {
 	// If BlogHost empty - setDefaults method will set BlogHost, BlogPath and BlogHttps.
 	BlogHost string `config:"BLOG_HOST"`
 	// BlogPath is path where blog is located (I guess it will always be "/blog/all").
+	// With "atom" BlogSource it's path to feed ("/blog/feed.atom").
 	BlogPath string `config:"BLOG_PATH"`
+	// BlogSource is a way to get posts from blog: "html" (default) or "atom".
+	BlogSource string `config:"BLOG_SOURCE"`
 	// BlogHTTPS flag shows should https protocol used instead of http or not.
 	BlogHTTPS bool `config:"BLOG_HTTPS"`
 	// BlogScanInterval is delay (in seconds) between blog's scans.
 	BlogScanInterval int `config:"BLOG_SCAN_INTERVAL,required"`
 	// BlogScanNetworkTimeout is http client's timeout (in seconds) during request to blog.
 	BlogScanNetworkTimeout int `config:"BLOG_SCAN_NETWORK_TIMEOUT,required"`
+	// BlogScanCron is cron expression ("minute hour day month weekday") of blog's scans.
+	// If set - BlogScanInterval is used only for readiness check.
+	BlogScanCron string `config:"BLOG_SCAN_CRON"`
+	// BlogScanJitter is max random delay (in seconds) of each blog's scan.
+	BlogScanJitter int `config:"BLOG_SCAN_JITTER"`
+	// BlogQuietHours are hours without blog's scans formatted as "HH:MM-HH:MM" (for example "22:00-07:00").
+	BlogQuietHours string `config:"BLOG_QUIET_HOURS"`
+	// BlogScanTimezone is timezone of BlogScanCron and BlogQuietHours (for example "Europe/Berlin").
+	// If empty - UTC.
+	BlogScanTimezone string `config:"BLOG_SCAN_TIMEZONE"`
+	// BlogFetchContent flag enables fetching content of new posts from their pages.
+	BlogFetchContent bool `config:"BLOG_FETCH_CONTENT"`
+	// BlogContentSelector is CSS selector of article's element on post's page.
+	// If empty - article.DefaultSelector is used.
+	BlogContentSelector string `config:"BLOG_CONTENT_SELECTOR"`
+	// BlogHTTPCache flag enables conditional requests to blog with validators of last
+	// processed response (ETag and Last-Modified), validators are stored in mongodb.
+	BlogHTTPCache bool `config:"BLOG_HTTP_CACHE"`
+	// BlogSources is list of names of blogs to scan. Each blog is configured with env vars
+	// with prefix BLOG_<NAME>_ (see blogSourceConfig). If BlogSources is empty - only one
+	// blog configured with BlogHost, BlogPath, BlogSource and BlogHTTPS is scanned.
+	BlogSources []string `config:"BLOG_SOURCES"`
+	// BlogRetryMaxAttempts is max count of attempts of request to blog (including first one).
+	// 1 disables retries.
+	BlogRetryMaxAttempts int `config:"BLOG_RETRY_MAX_ATTEMPTS"`
+	// BlogRetryBaseDelay is delay (in seconds) before first retry, each next delay is doubled.
+	BlogRetryBaseDelay int `config:"BLOG_RETRY_BASE_DELAY"`
+	// BlogRetryMaxDelay is max delay (in seconds) between attempts.
+	BlogRetryMaxDelay int `config:"BLOG_RETRY_MAX_DELAY"`
+	// BlogRetryJitter is fraction (from 0 to 1) of retry's delay which is randomized.
+	BlogRetryJitter float64 `config:"BLOG_RETRY_JITTER"`
+	// BlogRetryStatusCodes are status codes of blog's responses which are retried.
+	// If empty - 429, 500, 502, 503 and 504.
+	BlogRetryStatusCodes []int `config:"BLOG_RETRY_STATUS_CODES"`
 	// MongoHost is host of mongodb.
 	MongoHost string `config:"MONGO_HOST,required"`
 	// MongoUser is user for mongodb.
@@ -25,8 +128,47 @@ type appConfig struct {
 	MongoDatabase string `config:"MONGO_DATABASE"`
 	// MongoSRV flag shows should mongodb+srv protocol used instead of just mongo or not.
 	MongoSRV bool `config:"MONGO_SRV"`
-	// RabbitHost is host of rabbitmq.
-	RabbitHost string `config:"RABBIT_HOST,required"`
+	// MongoStorageMode is a way to store posts: "transactions" (default, requires
+	// replica set) or "standalone" (works with standalone mongodb instance).
+	MongoStorageMode string `config:"MONGO_STORAGE_MODE"`
+	// DetectUpdates flag enables detection of edits of published posts and publishing "post.updated" events.
+	DetectUpdates bool `config:"DETECT_UPDATES"`
+	// RemovalThreshold is count of consecutive successful scans where published post is missing
+	// in blog after which "post.removed" event is published. If empty - removals aren't detected.
+	RemovalThreshold int `config:"REMOVAL_THRESHOLD"`
+	// OutboxRelayInterval is delay (in seconds) between checks of outbox for pending posts.
+	OutboxRelayInterval int `config:"OUTBOX_RELAY_INTERVAL"`
+	// OutboxBatchSize is max count of pending posts published in one relay's iteration.
+	OutboxBatchSize int `config:"OUTBOX_BATCH_SIZE"`
+	// FirstRunPolicy is a way to handle posts of blog without published posts: "publish" (default,
+	// all posts are published), "seed" (all posts are saved as published without publishing),
+	// "newest" (only FirstRunNewest newest posts are published) or "since" (only posts since
+	// FirstRunSince are published).
+	FirstRunPolicy string `config:"FIRST_RUN_POLICY"`
+	// FirstRunNewest is count of posts published on first run with "newest" FirstRunPolicy.
+	FirstRunNewest int `config:"FIRST_RUN_NEWEST"`
+	// FirstRunSince is date ("2006-01-02") since which posts are published on first run with "since" FirstRunPolicy.
+	FirstRunSince string `config:"FIRST_RUN_SINCE"`
+	// DryRun flag enables dry run: published posts are read from storage, but new posts are
+	// only logged instead of being stored and published.
+	DryRun bool `config:"DRY_RUN"`
+	// LeaderElection flag enables leader election between replicas: only leader scans blogs and publishes posts.
+	LeaderElection bool `config:"LEADER_ELECTION"`
+	// LeaderLeaseTTL is duration (in seconds) of leader's lease, follower takes over within it if leader dies.
+	LeaderLeaseTTL int `config:"LEADER_LEASE_TTL"`
+	// LeaderID is unique replica's identifier in leader election. If empty - hostname with random suffix.
+	LeaderID string `config:"LEADER_ID"`
+	// AdminAddr is address for admin HTTP server with /healthz and /readyz endpoints (for example ":8080").
+	// If empty - admin server is not started.
+	AdminAddr string `config:"ADMIN_ADDR"`
+	// AdminReadyMaxScanAge is max duration (in seconds) since last successful scan of each source
+	// for service to be ready. If empty - 3 scan intervals of the source.
+	AdminReadyMaxScanAge int `config:"ADMIN_READY_MAX_SCAN_AGE"`
+	// Publisher is where events are published: "rabbit" (default) or "webhook".
+	Publisher string `config:"PUBLISHER"`
+
+	// RabbitHost is host of rabbitmq. Required for "rabbit" publisher.
+	RabbitHost string `config:"RABBIT_HOST"`
 	// RabbitUser is user for rabbitmq.
 	RabbitUser string `config:"RABBIT_USER"`
 	// RabbitPass is password for rabbitmq.
@@ -36,15 +178,302 @@ type appConfig struct {
 	// RabbitAmqps flag shows should amqps protocol be used instead of amqp or not.
 	RabbitAmqps bool `config:"RABBIT_AMQPS"`
 	// RabbitReconnectDelay is delay (in seconds) before attempting to reconnect to rabbit after loosing connection.
-	RabbitReconnectDelay int `config:"RABBIT_RECONNECT_DELAY,required"`
+	// Required for "rabbit" publisher.
+	RabbitReconnectDelay int `config:"RABBIT_RECONNECT_DELAY"`
+	// RabbitReconnectMaxDelay is max delay (in seconds) between attempts to reconnect,
+	// delay is doubled after each failed attempt.
+	RabbitReconnectMaxDelay int `config:"RABBIT_RECONNECT_MAX_DELAY"`
+	// RabbitFailFast flag makes publishing fail immediately while reconnecting to rabbit
+	// instead of waiting for reconnection.
+	RabbitFailFast bool `config:"RABBIT_FAIL_FAST"`
+	// RabbitConfirmTimeout is duration (in seconds) how long to wait for broker's confirmation of published post.
+	RabbitConfirmTimeout int `config:"RABBIT_CONFIRM_TIMEOUT"`
+	// RabbitMessageFormat is format of published messages: "envelope" (default), "legacy",
+	// "cloudevents-structured" or "cloudevents-binary".
+	RabbitMessageFormat string `config:"RABBIT_MESSAGE_FORMAT"`
+	// RabbitExchange is name of exchange where posts are published ("posts" by default).
+	RabbitExchange string `config:"RABBIT_EXCHANGE"`
+	// RabbitExchangeType is type of exchange: "fanout" (default), "topic", "direct" or "headers".
+	RabbitExchangeType string `config:"RABBIT_EXCHANGE_TYPE"`
+	// RabbitRoutingKey is template of messages' routing key, e.g. "{type}.{year}.{author}".
+	RabbitRoutingKey string `config:"RABBIT_ROUTING_KEY"`
+	// RabbitDropUnroutable flag makes posts returned by rabbit as unroutable marked as sent.
+	RabbitDropUnroutable bool `config:"RABBIT_DROP_UNROUTABLE"`
+	// RabbitTopologyFile is path of YAML or JSON file with exchanges, queues and bindings
+	// declared on each (re)connect.
+	RabbitTopologyFile string `config:"RABBIT_TOPOLOGY_FILE"`
+	// RabbitManagementURL is URL of rabbit's management HTTP API, e.g. "http://localhost:15672".
+	// It's used only by topology diff command.
+	RabbitManagementURL string `config:"RABBIT_MANAGEMENT_URL"`
+	// RabbitSpoolPath is path of file where posts are spooled while rabbit is unavailable.
+	// Spooling is disabled if it's empty.
+	RabbitSpoolPath string `config:"RABBIT_SPOOL_PATH"`
+	// RabbitSpoolMaxSize is max size (in megabytes) of spool file.
+	RabbitSpoolMaxSize int `config:"RABBIT_SPOOL_MAX_SIZE"`
+
+	// WebhookURLs are endpoints where "webhook" publisher POSTs events.
+	WebhookURLs []string `config:"WEBHOOK_URLS"`
+	// WebhookSecret is key of HMAC-SHA256 signature of webhook's requests.
+	WebhookSecret string `config:"WEBHOOK_SECRET"`
+	// WebhookTimeout is timeout (in seconds) of one webhook's request.
+	WebhookTimeout int `config:"WEBHOOK_TIMEOUT"`
+	// WebhookMaxAttempts is max count of delivery attempts to one endpoint (including first one).
+	WebhookMaxAttempts int `config:"WEBHOOK_MAX_ATTEMPTS"`
+	// WebhookRetryBaseDelay is delay (in seconds) before first retry, each next delay is doubled.
+	WebhookRetryBaseDelay int `config:"WEBHOOK_RETRY_BASE_DELAY"`
+	// WebhookRetryMaxDelay is max delay (in seconds) between attempts.
+	WebhookRetryMaxDelay int `config:"WEBHOOK_RETRY_MAX_DELAY"`
+}
+
+// blogSourceConfig is configuration of one blog to scan.
+// For blogs listed in BLOG_SOURCES it's parsed from env vars with
+// prefix BLOG_<NAME>_, for example BLOG_PKGSITE_HOST for blog "pkgsite".
+type blogSourceConfig struct {
+	// Name is blog's name, every post fetched from this blog is tagged with it.
+	Name string `config:"-"`
+	// Host is host where blog is located.
+	Host string `config:"HOST,required"`
+	// Path is path to page with all posts or to Atom feed.
+	Path string `config:"PATH,required"`
+	// HTTPS flag shows should https protocol used instead of http or not.
+	HTTPS bool `config:"HTTPS"`
+	// Parser is a way to get posts from blog: "html" (default) or "atom".
+	Parser string `config:"SOURCE"`
+	// ScanInterval is delay (in seconds) between blog's scans.
+	// If empty - appConfig.BlogScanInterval is used.
+	ScanInterval int `config:"SCAN_INTERVAL"`
+	// ScanNetworkTimeout is http client's timeout (in seconds) during request to blog.
+	// If empty - appConfig.BlogScanNetworkTimeout is used.
+	ScanNetworkTimeout int `config:"SCAN_NETWORK_TIMEOUT"`
+	// ScanCron is cron expression of blog's scans. If empty - appConfig.BlogScanCron is used.
+	ScanCron string `config:"SCAN_CRON"`
+	// ScanJitter is max random delay (in seconds) of blog's scans.
+	// If empty - appConfig.BlogScanJitter is used.
+	ScanJitter int `config:"SCAN_JITTER"`
+	// QuietHours are hours without blog's scans. If empty - appConfig.BlogQuietHours is used.
+	QuietHours string `config:"QUIET_HOURS"`
+	// FetchContent flag enables fetching content of new posts from their pages.
+	FetchContent bool `config:"FETCH_CONTENT"`
+	// ContentSelector is CSS selector of article's element on post's page.
+	// If empty - article.DefaultSelector is used.
+	ContentSelector string `config:"CONTENT_SELECTOR"`
+	// HTTPCache flag enables conditional requests to blog.
+	HTTPCache bool `config:"HTTP_CACHE"`
+}
+
+// loadConfig parses config from env and sets defaults.
+func loadConfig(log logger.Logger) (appConfig, error) {
+	var cfg appConfig
+	err := config.Parse(&cfg)
+	if err != nil {
+		return appConfig{}, errors.Wrap(err, "parse config")
+	}
+	cfg.setDefaults(log)
+
+	return cfg, nil
 }
 
 // setDefaults sets some default config variables if they are empty.
 func (c *appConfig) setDefaults(log logger.Logger) {
+	if c.BlogSource == "" {
+		c.BlogSource = blogSourceHTML
+	}
+
+	if c.FirstRunPolicy == "" {
+		c.FirstRunPolicy = string(scanner.FirstRunPublishAll)
+	}
+
+	if c.MongoStorageMode == "" {
+		c.MongoStorageMode = mongoStorageTransactions
+	}
+
+	if c.OutboxRelayInterval == 0 {
+		c.OutboxRelayInterval = defaultOutboxRelayInterval
+	}
+
+	if c.OutboxBatchSize == 0 {
+		c.OutboxBatchSize = defaultOutboxBatchSize
+	}
+
+	if c.LeaderLeaseTTL == 0 {
+		c.LeaderLeaseTTL = defaultLeaderLeaseTTL
+	}
+
+	if c.Publisher == "" {
+		c.Publisher = publisherRabbit
+	}
+
+	if c.WebhookTimeout == 0 {
+		c.WebhookTimeout = defaultWebhookTimeout
+	}
+
+	if c.WebhookMaxAttempts == 0 {
+		c.WebhookMaxAttempts = defaultWebhookMaxAttempts
+	}
+
+	if c.WebhookRetryBaseDelay == 0 {
+		c.WebhookRetryBaseDelay = defaultWebhookRetryBaseDelay
+	}
+
+	if c.WebhookRetryMaxDelay == 0 {
+		c.WebhookRetryMaxDelay = defaultWebhookRetryMaxDelay
+	}
+
+	if c.BlogRetryMaxAttempts == 0 {
+		c.BlogRetryMaxAttempts = defaultBlogRetryMaxAttempts
+	}
+
+	if c.BlogRetryBaseDelay == 0 {
+		c.BlogRetryBaseDelay = defaultBlogRetryBaseDelay
+	}
+
+	if c.BlogRetryMaxDelay == 0 {
+		c.BlogRetryMaxDelay = defaultBlogRetryMaxDelay
+	}
+
+	if c.BlogRetryJitter == 0 {
+		c.BlogRetryJitter = defaultBlogRetryJitter
+	}
+
+	if len(c.BlogRetryStatusCodes) == 0 {
+		c.BlogRetryStatusCodes = []int{
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		}
+	}
+
+	if c.RabbitConfirmTimeout == 0 {
+		c.RabbitConfirmTimeout = defaultRabbitConfirmTimeout
+	}
+
+	if c.RabbitReconnectMaxDelay == 0 {
+		c.RabbitReconnectMaxDelay = defaultRabbitReconnectMaxDelay
+	}
+
+	if c.RabbitExchange == "" {
+		c.RabbitExchange = publisher.DefaultExchange
+	}
+
+	if c.RabbitExchangeType == "" {
+		c.RabbitExchangeType = publisher.DefaultExchangeType
+	}
+
+	if c.RabbitSpoolMaxSize == 0 {
+		c.RabbitSpoolMaxSize = defaultRabbitSpoolMaxSize
+	}
+
+	if c.RabbitMessageFormat == "" {
+		c.RabbitMessageFormat = publisher.FormatEnvelope
+	}
+
 	if c.BlogHost == "" {
 		log.Warn("BlogHost config var is empty, setting BlogHost, BlogPath and BlogHTTPS to defaults")
 		c.BlogHost = "go.dev"
 		c.BlogPath = "/blog/all"
+		if c.BlogSource == blogSourceAtom {
+			c.BlogPath = "/blog/feed.atom"
+		}
 		c.BlogHTTPS = true
 	}
 }
+
+// scannerOptions returns scanner's options. Must be called after setDefaults.
+func (c *appConfig) scannerOptions() (scanner.Options, error) {
+	options := scanner.Options{
+		DetectUpdates:    c.DetectUpdates,
+		RemovalThreshold: c.RemovalThreshold,
+		FirstRun: scanner.FirstRunPolicy{
+			Mode:   scanner.FirstRunMode(c.FirstRunPolicy),
+			Newest: c.FirstRunNewest,
+		},
+	}
+
+	switch options.FirstRun.Mode {
+	case scanner.FirstRunPublishAll, scanner.FirstRunSeed:
+	case scanner.FirstRunNewest:
+		if c.FirstRunNewest <= 0 {
+			return scanner.Options{}, errors.New("FIRST_RUN_NEWEST must be positive with \"newest\" first run policy")
+		}
+	case scanner.FirstRunSince:
+		since, err := time.Parse(firstRunSinceLayout, c.FirstRunSince)
+		if err != nil {
+			return scanner.Options{}, errors.Wrap(err, "parse FIRST_RUN_SINCE")
+		}
+		options.FirstRun.Since = since
+	default:
+		return scanner.Options{}, errors.Errorf("unknown first run policy %q", c.FirstRunPolicy)
+	}
+
+	return options, nil
+}
+
+// blogSources returns configurations of all blogs to scan.
+// Must be called after setDefaults.
+func (c *appConfig) blogSources() ([]blogSourceConfig, error) {
+	if len(c.BlogSources) == 0 {
+		return []blogSourceConfig{{
+			Name:               c.BlogHost,
+			Host:               c.BlogHost,
+			Path:               c.BlogPath,
+			HTTPS:              c.BlogHTTPS,
+			Parser:             c.BlogSource,
+			ScanInterval:       c.BlogScanInterval,
+			ScanNetworkTimeout: c.BlogScanNetworkTimeout,
+			ScanCron:           c.BlogScanCron,
+			ScanJitter:         c.BlogScanJitter,
+			QuietHours:         c.BlogQuietHours,
+			FetchContent:       c.BlogFetchContent,
+			ContentSelector:    c.BlogContentSelector,
+			HTTPCache:          c.BlogHTTPCache,
+		}}, nil
+	}
+
+	sources := make([]blogSourceConfig, 0, len(c.BlogSources))
+	names := make(map[string]bool, len(c.BlogSources))
+	for _, name := range c.BlogSources {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		if names[name] {
+			return nil, errors.Errorf("blog %q listed twice", name)
+		}
+		names[name] = true
+
+		// Name "go.dev" becomes prefix "BLOG_GO_DEV_".
+		prefix := "BLOG_" + strings.NewReplacer("-", "_", ".", "_").Replace(strings.ToUpper(name)) + "_"
+
+		source := blogSourceConfig{Name: name}
+		err := config.ParseWithPrefix(prefix, &source)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse config of blog %q", name)
+		}
+
+		if source.Parser == "" {
+			source.Parser = blogSourceHTML
+		}
+		if source.ScanInterval == 0 {
+			source.ScanInterval = c.BlogScanInterval
+		}
+		if source.ScanNetworkTimeout == 0 {
+			source.ScanNetworkTimeout = c.BlogScanNetworkTimeout
+		}
+		if source.ScanCron == "" {
+			source.ScanCron = c.BlogScanCron
+		}
+		if source.ScanJitter == 0 {
+			source.ScanJitter = c.BlogScanJitter
+		}
+		if source.QuietHours == "" {
+			source.QuietHours = c.BlogQuietHours
+		}
+
+		sources = append(sources, source)
+	}
+
+	return sources, nil
+}
@@ -3,6 +3,7 @@ package app
 import (
 	"context"
 
+	"gbu-scanner/pkg/logger"
 	"gbu-scanner/pkg/wrappers/mongo"
 
 	"github.com/pkg/errors"
@@ -22,3 +23,11 @@ func makeConnections(ctx context.Context, cfg appConfig) (*mongo.Client, error)
 
 	return mongo, nil
 }
+
+// disconnect disconnects mongo client, it's called deferred.
+func disconnect(ctx context.Context, mongo *mongo.Client, log logger.Logger) {
+	err := mongo.Disconnect(ctx) // Disconnects without error if context closed.
+	if err != nil {
+		log.Error(errors.Wrap(err, "can't disconnect mongo client"))
+	}
+}
@@ -2,13 +2,21 @@ package app
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"net/http"
+	"net/url"
+	"os"
 	"time"
 
-	"gbu-scanner/internal/blog"
+	"gbu-scanner/internal/dryrun"
+	"gbu-scanner/internal/leader"
+	"gbu-scanner/internal/outbox"
 	"gbu-scanner/internal/posts"
 	"gbu-scanner/internal/publisher"
 	"gbu-scanner/internal/scanner"
+	"gbu-scanner/internal/topology"
+	"gbu-scanner/internal/webhook"
 
 	"gbu-scanner/pkg/logger"
 
@@ -16,42 +24,221 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// dependencies are scanner's and relay's dependencies.
+type dependencies struct {
+	sources   []scanner.Source
+	publisher scanner.Publisher
+	posts     scanner.Posts
+	outbox    scanner.Outbox
+}
+
 // makeDependencies maeks all scanner's dependencies.
-func makeDependencies(
-	ctx context.Context,
-	cfg appConfig,
-	mongo *mongo.Client,
-	log logger.Logger,
-) (
-	scanner.Blog,
-	scanner.Publisher,
-	scanner.Posts,
-	error,
-) {
+func makeDependencies(ctx context.Context, cfg appConfig, mongo *mongo.Client, log logger.Logger) (dependencies, error) {
+	if cfg.DryRun {
+		return makeDryRunDependencies(ctx, cfg, mongo, log)
+	}
+
+	publisher, err := makePublisher(ctx, cfg, log)
+	if err != nil {
+		return dependencies{}, errors.Wrap(err, "make publisher")
+	}
+
+	posts, err := makePosts(ctx, cfg, mongo, log)
+	if err != nil {
+		return dependencies{}, errors.Wrap(err, "make posts")
+	}
+
+	outbox := outbox.New(mongo, cfg.MongoDatabase, log)
+
+	err = outbox.Init(ctx)
+	if err != nil {
+		return dependencies{}, errors.Wrap(err, "init outbox")
+	}
+
+	sources, err := makeSources(ctx, cfg, mongo, log)
+	if err != nil {
+		return dependencies{}, errors.Wrap(err, "make sources")
+	}
+
+	return dependencies{
+		sources:   sources,
+		publisher: publisher,
+		posts:     posts,
+		outbox:    outbox,
+	}, nil
+}
+
+// makePublisher makes and initializes publisher depending on configured publisher's kind.
+func makePublisher(ctx context.Context, cfg appConfig, log logger.Logger) (scanner.Publisher, error) {
+	switch cfg.Publisher {
+	case publisherRabbit:
+		return makeRabbitPublisher(ctx, cfg, log)
+	case publisherWebhook:
+		return makeWebhookPublisher(cfg, log)
+	default:
+		return nil, errors.Errorf("unknown publisher %q", cfg.Publisher)
+	}
+}
+
+// makeRabbitPublisher makes and initializes publisher to rabbitmq.
+func makeRabbitPublisher(ctx context.Context, cfg appConfig, log logger.Logger) (*publisher.Publisher, error) {
+	if cfg.RabbitHost == "" || cfg.RabbitReconnectDelay == 0 {
+		return nil, errors.New("RABBIT_HOST and RABBIT_RECONNECT_DELAY are required for rabbit publisher")
+	}
+
+	var brokerTopology topology.Topology
+	if cfg.RabbitTopologyFile != "" {
+		var err error
+		brokerTopology, err = topology.Load(cfg.RabbitTopologyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "load topology")
+		}
+	}
+
 	publisher := publisher.New(publisher.RabbitConfig{
-		Host:           cfg.RabbitHost,
-		User:           cfg.RabbitUser,
-		Pass:           cfg.RabbitPass,
-		Vhost:          cfg.RabbitVhost,
-		Amqps:          cfg.RabbitAmqps,
-		ReconnectDelay: time.Duration(cfg.RabbitReconnectDelay) * time.Second,
+		Host:              cfg.RabbitHost,
+		User:              cfg.RabbitUser,
+		Pass:              cfg.RabbitPass,
+		Vhost:             cfg.RabbitVhost,
+		Amqps:             cfg.RabbitAmqps,
+		ReconnectDelay:    time.Duration(cfg.RabbitReconnectDelay) * time.Second,
+		ReconnectMaxDelay: time.Duration(cfg.RabbitReconnectMaxDelay) * time.Second,
+		FailFast:          cfg.RabbitFailFast,
+		ConfirmTimeout:    time.Duration(cfg.RabbitConfirmTimeout) * time.Second,
+		Exchange:          cfg.RabbitExchange,
+		ExchangeType:      cfg.RabbitExchangeType,
+		RoutingKey:        cfg.RabbitRoutingKey,
+		DropUnroutable:    cfg.RabbitDropUnroutable,
+		Topology:          brokerTopology,
+		SpoolPath:         cfg.RabbitSpoolPath,
+		SpoolMaxSize:      int64(cfg.RabbitSpoolMaxSize) << 20,
+		MessageFormat:     cfg.RabbitMessageFormat,
 	}, log)
 
 	err := publisher.Init(ctx, ctx)
 	if err != nil {
-		return nil, nil, nil, errors.Wrap(err, "init publisher")
+		return nil, errors.Wrap(err, "init publisher")
 	}
 
-	posts := posts.New(mongo, cfg.MongoDatabase, log)
+	return publisher, nil
+}
+
+// makeWebhookPublisher makes publisher POSTing events to webhook's endpoints.
+// Redirects are not followed, so endpoint responding with redirect fails delivery.
+func makeWebhookPublisher(cfg appConfig, log logger.Logger) (*webhook.Publisher, error) {
+	if len(cfg.WebhookURLs) == 0 || cfg.WebhookSecret == "" {
+		return nil, errors.New("WEBHOOK_URLS and WEBHOOK_SECRET are required for webhook publisher")
+	}
+
+	for i, endpointURL := range cfg.WebhookURLs {
+		// Parsing error isn't wrapped as it contains url with possible credentials.
+		u, err := url.Parse(endpointURL)
+		if err != nil {
+			return nil, errors.Errorf("webhook's url #%d is invalid", i+1)
+		}
+
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, errors.Errorf("webhook's url %q is not http(s) url", u.Redacted())
+		}
+	}
+
+	client := &http.Client{
+		Timeout: time.Duration(cfg.WebhookTimeout) * time.Second,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return webhook.New(webhook.Config{
+		URLs:        cfg.WebhookURLs,
+		Secret:      cfg.WebhookSecret,
+		MaxAttempts: cfg.WebhookMaxAttempts,
+		BaseDelay:   time.Duration(cfg.WebhookRetryBaseDelay) * time.Second,
+		MaxDelay:    time.Duration(cfg.WebhookRetryMaxDelay) * time.Second,
+	}, client, log), nil
+}
+
+// makeDryRunDependencies makes scanner's dependencies which read published posts from storage,
+// but don't write anything to it and don't publish. Blogs are scanned without http cache, as
+// committing cache would make real scanner skip posts.
+func makeDryRunDependencies(ctx context.Context, cfg appConfig, mongo *mongo.Client, log logger.Logger) (dependencies, error) {
+	log.Warn("dry run: posts are not stored and published")
+
+	posts, err := makeReadOnlyPosts(cfg, mongo, log)
+	if err != nil {
+		return dependencies{}, errors.Wrap(err, "make posts")
+	}
+
+	sources, err := makeSources(ctx, cfg, nil, log)
+	if err != nil {
+		return dependencies{}, errors.Wrap(err, "make sources")
+	}
+
+	storage := dryrun.New(posts, log)
+
+	return dependencies{
+		sources:   sources,
+		publisher: dryrun.NewPublisher(log),
+		posts:     storage,
+		outbox:    storage,
+	}, nil
+}
+
+// makeElector makes leader elector of scanning replicas.
+func makeElector(cfg appConfig, mongo *mongo.Client, log logger.Logger) (*leader.Elector, error) {
+	id := cfg.LeaderID
+	if id == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, errors.Wrap(err, "get hostname")
+		}
+
+		// Random suffix keeps id unique even if replicas have same hostname.
+		suffix := make([]byte, 4)
+		_, err = rand.Read(suffix)
+		if err != nil {
+			return nil, errors.Wrap(err, "read random bytes")
+		}
+
+		id = hostname + "-" + hex.EncodeToString(suffix)
+	}
+
+	ttl := time.Duration(cfg.LeaderLeaseTTL) * time.Second
+
+	return leader.New(mongo, cfg.MongoDatabase, leaderLease, id, ttl, log), nil
+}
+
+// postsStorage is scanner.Posts implementation which initializes database before writing to it.
+type postsStorage interface {
+	scanner.Posts
+	Init(ctx context.Context) error
+}
+
+// makePosts makes and initializes scanner.Posts implementation depending on configured storage mode.
+func makePosts(ctx context.Context, cfg appConfig, mongo *mongo.Client, log logger.Logger) (scanner.Posts, error) {
+	posts, err := makeReadOnlyPosts(cfg, mongo, log)
+	if err != nil {
+		return nil, err
+	}
 
 	err = posts.Init(ctx)
 	if err != nil {
-		return nil, nil, nil, errors.Wrap(err, "init database")
+		return nil, errors.Wrap(err, "init database")
 	}
 
-	blog := blog.New(cfg.BlogHost, cfg.BlogPath, cfg.BlogHTTPS, &http.Client{
-		Timeout: time.Duration(cfg.BlogScanNetworkTimeout) * time.Second,
-	}, log)
+	return posts, nil
+}
 
-	return blog, publisher, posts, nil
+// makeReadOnlyPosts makes scanner.Posts implementation depending on configured storage mode without
+// initializing database (inserting posts' document, creating indexes and migrating), so commands
+// which only read published posts (list, diff, replay and dry run) don't write anything to it.
+func makeReadOnlyPosts(cfg appConfig, mongo *mongo.Client, log logger.Logger) (postsStorage, error) {
+	switch cfg.MongoStorageMode {
+	case mongoStorageTransactions:
+		return posts.New(mongo, cfg.MongoDatabase, log), nil
+	case mongoStorageStandalone:
+		return posts.NewStandalone(mongo, cfg.MongoDatabase, log), nil
+	default:
+		return nil, errors.Errorf("unknown mongo storage mode %q", cfg.MongoStorageMode)
+	}
 }
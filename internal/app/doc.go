@@ -1,4 +1,5 @@
 // Package app provides function Run which is like main, but returns error that can
-// be handled in real main function. This approach allows to call os.Exit or log.Fatal(...)
+// be handled in real main function. Functions ScanOnce, List, Fetch, Diff and Replay are
+// the same for binary's other commands. This approach allows to call os.Exit or log.Fatal(...)
 // once in main instead of calling it on each error.
 package app
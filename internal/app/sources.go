@@ -0,0 +1,153 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"gbu-scanner/internal/article"
+	"gbu-scanner/internal/blog"
+	"gbu-scanner/internal/httpcache"
+	"gbu-scanner/internal/scanner"
+
+	"gbu-scanner/pkg/logger"
+	"gbu-scanner/pkg/schedule"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// makeSources makes scanner's sources for all configured blogs.
+// If mongo is nil, sources are made without http cache.
+func makeSources(ctx context.Context, cfg appConfig, mongo *mongo.Client, log logger.Logger) ([]scanner.Source, error) {
+	sourceConfigs, err := cfg.blogSources()
+	if err != nil {
+		return nil, errors.Wrap(err, "get blog sources")
+	}
+
+	loc := time.UTC
+	if cfg.BlogScanTimezone != "" {
+		loc, err = time.LoadLocation(cfg.BlogScanTimezone)
+		if err != nil {
+			return nil, errors.Wrap(err, "load scan timezone")
+		}
+	}
+
+	// Jitter out of [0, 1] would make retry's delay negative or longer than max delay.
+	if cfg.BlogRetryJitter < 0 || cfg.BlogRetryJitter > 1 {
+		return nil, errors.Errorf("blog retry jitter must be from 0 to 1, got %v", cfg.BlogRetryJitter)
+	}
+
+	// Created on first blog with http cache.
+	var cacheStore *httpcache.Store
+
+	sources := make([]scanner.Source, 0, len(sourceConfigs))
+	for _, sourceConfig := range sourceConfigs {
+		source := scanner.Source{
+			Name: sourceConfig.Name,
+		}
+
+		source.Schedule, err = makeSchedule(sourceConfig, loc)
+		if err != nil {
+			return nil, errors.Wrapf(err, "make schedule of blog %q", sourceConfig.Name)
+		}
+
+		var httpClient blog.HTTPClient = &http.Client{
+			Timeout: time.Duration(sourceConfig.ScanNetworkTimeout) * time.Second,
+		}
+
+		// Retries are under cache, so retried request has same conditional headers.
+		if cfg.BlogRetryMaxAttempts > 1 {
+			httpClient = blog.NewRetryingClient(httpClient, blog.RetryPolicy{
+				MaxAttempts:          cfg.BlogRetryMaxAttempts,
+				BaseDelay:            time.Duration(cfg.BlogRetryBaseDelay) * time.Second,
+				MaxDelay:             time.Duration(cfg.BlogRetryMaxDelay) * time.Second,
+				Jitter:               cfg.BlogRetryJitter,
+				RetryableStatusCodes: cfg.BlogRetryStatusCodes,
+			}, log)
+		}
+
+		if sourceConfig.HTTPCache && mongo != nil {
+			if cacheStore == nil {
+				cacheStore = httpcache.New(mongo, cfg.MongoDatabase, log)
+
+				err = cacheStore.Init(ctx)
+				if err != nil {
+					return nil, errors.Wrap(err, "init http cache store")
+				}
+			}
+
+			cachingClient := blog.NewCachingClient(httpClient, cacheStore, log)
+			httpClient = cachingClient
+			source.Cache = cachingClient
+		}
+
+		source.Blog, err = makeBlog(sourceConfig, httpClient, log)
+		if err != nil {
+			return nil, errors.Wrapf(err, "make blog %q", sourceConfig.Name)
+		}
+
+		if sourceConfig.FetchContent {
+			source.Enricher = makeEnricher(sourceConfig, log)
+		}
+
+		sources = append(sources, source)
+	}
+
+	return sources, nil
+}
+
+// makeBlog makes scanner.Blog implementation depending on configured blog's parser.
+func makeBlog(cfg blogSourceConfig, httpClient blog.HTTPClient, log logger.Logger) (scanner.Blog, error) {
+	switch cfg.Parser {
+	case blogSourceHTML:
+		return blog.New(cfg.Host, cfg.Path, cfg.HTTPS, httpClient, log), nil
+	case blogSourceAtom:
+		return blog.NewAtom(cfg.Host, cfg.Path, cfg.HTTPS, httpClient, log), nil
+	default:
+		return nil, errors.Errorf("unknown blog source %q", cfg.Parser)
+	}
+}
+
+// makeSchedule makes schedule of blog's scans: cron or fixed rate with optional jitter and quiet hours.
+func makeSchedule(cfg blogSourceConfig, loc *time.Location) (scanner.Schedule, error) {
+	var s schedule.Schedule
+	if cfg.ScanCron != "" {
+		cron, err := schedule.ParseCron(cfg.ScanCron, loc)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse cron expression")
+		}
+		s = cron
+	} else {
+		if cfg.ScanInterval <= 0 {
+			return nil, errors.Errorf("scan interval must be positive if cron expression is empty, got %d", cfg.ScanInterval)
+		}
+		s = schedule.Every(time.Duration(cfg.ScanInterval) * time.Second)
+	}
+
+	if cfg.ScanJitter != 0 {
+		s = schedule.WithJitter(s, time.Duration(cfg.ScanJitter)*time.Second)
+	}
+
+	if cfg.QuietHours != "" {
+		quiet, err := schedule.WithQuietHours(s, cfg.QuietHours, loc)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse quiet hours")
+		}
+		s = quiet
+	}
+
+	return s, nil
+}
+
+// makeEnricher makes scanner.Enricher implementation to fetch content of blog's posts.
+func makeEnricher(cfg blogSourceConfig, log logger.Logger) scanner.Enricher {
+	selector := cfg.ContentSelector
+	if selector == "" {
+		selector = article.DefaultSelector
+	}
+
+	return article.New(selector, &http.Client{
+		Timeout: time.Duration(cfg.ScanNetworkTimeout) * time.Second,
+	}, log)
+}
@@ -0,0 +1,148 @@
+package article
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"gbu-scanner/internal/entity"
+	"gbu-scanner/internal/scanner"
+
+	"gbu-scanner/pkg/logger"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/pkg/errors"
+)
+
+// Article is implementation for scanner.Enricher interface.
+type Article struct {
+	selector   string // CSS selector of article's element on post's page
+	httpClient HTTPClient
+	log        logger.Logger
+}
+
+var _ scanner.Enricher = &Article{}
+
+// New returns scanner.Enricher implementation which extracts
+// article's content from element matched by selector.
+func New(selector string, client HTTPClient, log logger.Logger) *Article {
+	return &Article{
+		selector:   selector,
+		httpClient: client,
+		log:        log,
+	}
+}
+
+func (a *Article) Enrich(ctx context.Context, post entity.Post) (entity.Post, error) {
+	base, err := url.Parse(post.URL)
+	if err != nil {
+		return post, errors.Wrap(err, "parse post's url")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, post.URL, nil)
+	if err != nil {
+		return post, errors.Wrap(err, "create request")
+	}
+
+	res, err := a.httpClient.Do(req)
+	if err != nil {
+		return post, errors.Wrap(err, "execute request")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return post, errors.Errorf("response status code is not OK (%s)", res.Status)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(res.Body)
+	if err != nil {
+		return post, errors.Wrap(err, "get goquery document from response")
+	}
+
+	article := doc.Find(a.selector).First()
+	if article.Length() == 0 {
+		return post, errors.Errorf("no article element matched by %q", a.selector)
+	}
+
+	article.Find(removedSelector).Remove()
+	makeURLsAbsolute(article, base)
+
+	html, err := article.Html()
+	if err != nil {
+		return post, errors.Wrap(err, "render article's HTML")
+	}
+
+	markdown := renderMarkdown(article)
+
+	headings := []string{}
+	article.Find("h2, h3, h4, h5, h6").Each(func(i int, h *goquery.Selection) {
+		headings = append(headings, strings.TrimSpace(h.Text()))
+	})
+
+	post.Content = &entity.PostContent{
+		HTML:           strings.TrimSpace(html),
+		Markdown:       markdown,
+		ReadingMinutes: readingMinutes(article.Text()),
+		Headings:       headings,
+		Tags:           findTags(doc),
+	}
+
+	return post, nil
+}
+
+// makeURLsAbsolute resolves links' and images' relative urls against post's url,
+// so content can be rendered outside of blog.
+func makeURLsAbsolute(article *goquery.Selection, base *url.URL) {
+	resolve := func(attr string) func(int, *goquery.Selection) {
+		return func(i int, s *goquery.Selection) {
+			value, _ := s.Attr(attr)
+			ref, err := url.Parse(value)
+			if err != nil {
+				return
+			}
+			s.SetAttr(attr, base.ResolveReference(ref).String())
+		}
+	}
+
+	article.Find("a[href]").Each(resolve("href"))
+	article.Find("img[src]").Each(resolve("src"))
+}
+
+// readingMinutes returns estimated time (in minutes) to read text, at least 1 minute.
+func readingMinutes(text string) int {
+	words := len(strings.Fields(text))
+	minutes := (words + wordsPerMinute - 1) / wordsPerMinute
+	if minutes < 1 {
+		minutes = 1
+	}
+	return minutes
+}
+
+// findTags returns post's tags from keywords meta tag and from tags' links
+// (old blog's design showed them under article).
+func findTags(doc *goquery.Document) []string {
+	tags := []string{}
+	isAdded := make(map[string]bool)
+
+	add := func(tag string) {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || isAdded[tag] {
+			return
+		}
+		isAdded[tag] = true
+		tags = append(tags, tag)
+	}
+
+	if keywords, ok := doc.Find(`meta[name="keywords"]`).Attr("content"); ok {
+		for _, tag := range strings.Split(keywords, ",") {
+			add(tag)
+		}
+	}
+
+	doc.Find(".tags a").Each(func(i int, s *goquery.Selection) {
+		add(s.Text())
+	})
+
+	return tags
+}
@@ -0,0 +1,11 @@
+package article
+
+// DefaultSelector is CSS selector of article's element on go.dev's blog post page.
+const DefaultSelector = ".Article"
+
+// removedSelector is CSS selector of elements inside article which are not article's
+// content: title and authors (they are post's fields) and links to other posts.
+const removedSelector = "h1, .author, .prevnext, .Article-prevnext"
+
+// wordsPerMinute is average reading speed used to estimate reading time.
+const wordsPerMinute = 200
@@ -0,0 +1,4 @@
+// Package article provides implementation for scanner.Enricher interface -
+// it fetches post's page and extracts article's content: HTML, Markdown rendering,
+// reading time estimate, headings and tags.
+package article
@@ -0,0 +1,153 @@
+package article
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	// spacesRegexp matches whitespaces to collapse in text outside of <pre>.
+	spacesRegexp = regexp.MustCompile(`\s+`)
+	// newlinesRegexp matches 3+ newlines (with whitespaces between) to collapse to one blank line.
+	newlinesRegexp = regexp.MustCompile(`\n[ \t]*(\n[ \t]*)+\n`)
+)
+
+// renderMarkdown renders children of article's element to Markdown. Only elements common
+// in blog posts are rendered specially (headings, paragraphs, lists, code, links, images,
+// emphasis, quotes), text of other elements is rendered as is.
+func renderMarkdown(article *goquery.Selection) string {
+	b := &strings.Builder{}
+	for _, n := range article.Nodes {
+		renderChildren(b, n)
+	}
+
+	md := newlinesRegexp.ReplaceAllString(b.String(), "\n\n")
+
+	lines := strings.Split(md, "\n")
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], " \t")
+	}
+
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func renderChildren(b *strings.Builder, n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		renderNode(b, c)
+	}
+}
+
+// renderNode renders node with it's children to Markdown.
+func renderNode(b *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		text := spacesRegexp.ReplaceAllString(n.Data, " ")
+		// Whitespaces between block elements must not indent next line.
+		if rendered := b.String(); rendered == "" || strings.HasSuffix(rendered, "\n") {
+			text = strings.TrimLeft(text, " ")
+		}
+		b.WriteString(text)
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		fmt.Fprintf(b, "\n\n%s %s\n\n", strings.Repeat("#", level), strings.TrimSpace(renderInline(n)))
+	case atom.P, atom.Div, atom.Section, atom.Figure:
+		b.WriteString("\n\n")
+		renderChildren(b, n)
+		b.WriteString("\n\n")
+	case atom.Br:
+		b.WriteString("\n")
+	case atom.Hr:
+		b.WriteString("\n\n---\n\n")
+	case atom.Pre:
+		fmt.Fprintf(b, "\n\n```\n%s\n```\n\n", strings.Trim(textContent(n), "\n"))
+	case atom.Code:
+		fmt.Fprintf(b, "`%s`", textContent(n))
+	case atom.A:
+		fmt.Fprintf(b, "[%s](%s)", strings.TrimSpace(renderInline(n)), attr(n, "href"))
+	case atom.Img:
+		fmt.Fprintf(b, "![%s](%s)", attr(n, "alt"), attr(n, "src"))
+	case atom.Strong, atom.B:
+		fmt.Fprintf(b, "**%s**", strings.TrimSpace(renderInline(n)))
+	case atom.Em, atom.I:
+		fmt.Fprintf(b, "*%s*", strings.TrimSpace(renderInline(n)))
+	case atom.Ul, atom.Ol:
+		renderList(b, n)
+	case atom.Blockquote:
+		quote := newlinesRegexp.ReplaceAllString(strings.TrimSpace(renderInline(n)), "\n\n")
+		b.WriteString("\n\n")
+		for _, line := range strings.Split(quote, "\n") {
+			fmt.Fprintf(b, "> %s\n", line)
+		}
+		b.WriteString("\n")
+	case atom.Script, atom.Style, atom.Noscript:
+	default:
+		renderChildren(b, n)
+	}
+}
+
+// renderList renders list's items, ordered list's items are numbered.
+func renderList(b *strings.Builder, n *html.Node) {
+	b.WriteString("\n\n")
+
+	number := 1
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || c.DataAtom != atom.Li {
+			continue
+		}
+
+		marker := "-"
+		if n.DataAtom == atom.Ol {
+			marker = fmt.Sprintf("%d.", number)
+			number++
+		}
+
+		item := newlinesRegexp.ReplaceAllString(strings.TrimSpace(renderInline(c)), "\n")
+		// Continuation lines of item are indented to stay inside item.
+		item = strings.ReplaceAll(item, "\n", "\n   ")
+		fmt.Fprintf(b, "%s %s\n", marker, item)
+	}
+
+	b.WriteString("\n")
+}
+
+// renderInline renders node's children to separate string.
+func renderInline(n *html.Node) string {
+	b := &strings.Builder{}
+	renderChildren(b, n)
+	return b.String()
+}
+
+// textContent returns node's text as is (without collapsing whitespaces).
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+
+	b := &strings.Builder{}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(textContent(c))
+	}
+	return b.String()
+}
+
+// attr returns value of node's attribute with key or empty string.
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
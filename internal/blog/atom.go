@@ -0,0 +1,154 @@
+package blog
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gbu-scanner/internal/entity"
+	"gbu-scanner/internal/metrics"
+	"gbu-scanner/internal/scanner"
+
+	"gbu-scanner/pkg/logger"
+
+	"github.com/pkg/errors"
+)
+
+// Atom is implementation for scanner.Blog interface.
+// It gets posts from blog's Atom feed (go.dev/blog/feed.atom) instead of
+// scraping HTML, so changes in blog's markup don't break it.
+type Atom struct {
+	host       string // Host where blog hosted (go.dev)
+	feedPath   string // Path to Atom feed (/blog/feed.atom)
+	protocol   string // Protocol to use (http or https)
+	httpClient HTTPClient
+	log        logger.Logger
+}
+
+var _ scanner.Blog = &Atom{}
+
+// NewAtom returns scanner.Blog implementation via Atom feed.
+func NewAtom(host string, feedPath string, https bool, client HTTPClient, log logger.Logger) *Atom {
+	protocol := "http"
+	if https {
+		protocol = "https"
+	}
+	return &Atom{
+		host:       host,
+		feedPath:   feedPath,
+		protocol:   protocol,
+		httpClient: client,
+		log:        log,
+	}
+}
+
+// atomFeed is a part of Atom feed (RFC 4287) required to get posts.
+type atomFeed struct {
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID        string       `xml:"id"`
+	Title     string       `xml:"title"`
+	Published string       `xml:"published"`
+	Updated   string       `xml:"updated"`
+	Summary   string       `xml:"summary"`
+	Links     []atomLink   `xml:"link"`
+	Authors   []atomAuthor `xml:"author"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+func (a *Atom) GetPosts(ctx context.Context) ([]entity.Post, error) {
+	url := fmt.Sprintf("%s://%s%s", a.protocol, a.host, a.feedPath)
+	res, err := fetch(ctx, a.httpClient, url)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch feed")
+	}
+	defer res.Body.Close()
+
+	var feed atomFeed
+	err = xml.NewDecoder(res.Body).Decode(&feed)
+	if err != nil {
+		return nil, errors.Wrap(err, "decode feed")
+	}
+
+	posts := make([]entity.Post, 0, len(feed.Entries))
+	for _, e := range feed.Entries {
+		post, err := a.entryToPost(e)
+		if err != nil {
+			a.log.Error(errors.Wrapf(err, "can't convert entry %q to post", e.ID))
+			continue
+		}
+		posts = append(posts, post)
+	}
+
+	// Feed isn't required to be ordered, but scanner.Blog's contract is newest to oldest.
+	sort.SliceStable(posts, func(i, j int) bool {
+		return posts[i].Date.After(posts[j].Date)
+	})
+
+	return posts, nil
+}
+
+// entryToPost converts Atom feed's entry to entity.Post.
+func (a *Atom) entryToPost(e atomEntry) (entity.Post, error) {
+	updated, err := time.Parse(atomDateLayout, strings.TrimSpace(e.Updated))
+	if err != nil {
+		metrics.ParseErrors.WithLabelValues(parserAtom, "updated").Inc()
+		return entity.Post{}, errors.Wrap(err, "parse updated date")
+	}
+
+	// Published date is optional in Atom, updated is used instead then.
+	published := updated
+	if strings.TrimSpace(e.Published) != "" {
+		published, err = time.Parse(atomDateLayout, strings.TrimSpace(e.Published))
+		if err != nil {
+			metrics.ParseErrors.WithLabelValues(parserAtom, "date").Inc()
+			return entity.Post{}, errors.Wrap(err, "parse published date")
+		}
+	}
+
+	url := ""
+	for _, l := range e.Links {
+		// Link without rel attribute is alternate link by RFC 4287.
+		if l.Rel == "" || l.Rel == "alternate" {
+			url = strings.TrimSpace(l.Href)
+			break
+		}
+	}
+	if url == "" {
+		metrics.ParseErrors.WithLabelValues(parserAtom, "url").Inc()
+		return entity.Post{}, errors.New("no alternate link")
+	}
+	if strings.HasPrefix(url, "/") {
+		url = fmt.Sprintf("%s://%s%s", a.protocol, a.host, url)
+	}
+
+	authors := make([]string, 0, len(e.Authors))
+	for _, author := range e.Authors {
+		if name := strings.TrimSpace(author.Name); name != "" {
+			authors = append(authors, name)
+		}
+	}
+
+	return entity.Post{
+		ID:      strings.TrimSpace(e.ID),
+		Title:   strings.TrimSpace(e.Title),
+		Date:    published,
+		Updated: updated,
+		Author:  strings.Join(authors, ", "),
+		Summary: strings.TrimSpace(e.Summary),
+		URL:     url,
+	}, nil
+}
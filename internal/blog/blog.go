@@ -3,11 +3,11 @@ package blog
 import (
 	"context"
 	"fmt"
-	"net/http"
 	"strings"
 	"time"
 
 	"gbu-scanner/internal/entity"
+	"gbu-scanner/internal/metrics"
 	"gbu-scanner/internal/scanner"
 
 	"gbu-scanner/pkg/logger"
@@ -17,6 +17,7 @@ import (
 )
 
 // Blog is implementation for scanner.Blog interface.
+// It scrapes posts from blog's HTML page with all posts.
 type Blog struct {
 	host       string // Host where blog hosted (go.dev)
 	blogPath   string // Path to all posts (/blog/all)
@@ -44,21 +45,12 @@ func New(host string, blogPath string, https bool, client HTTPClient, log logger
 
 func (p *Blog) GetPosts(ctx context.Context) ([]entity.Post, error) {
 	url := fmt.Sprintf("%s://%s%s", p.protocol, p.host, p.blogPath)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	res, err := fetch(ctx, p.httpClient, url)
 	if err != nil {
-		return nil, errors.Wrap(err, "create request")
-	}
-
-	res, err := p.httpClient.Do(req)
-	if err != nil {
-		return nil, errors.Wrap(err, "execute request")
+		return nil, errors.Wrap(err, "fetch blog page")
 	}
 	defer res.Body.Close()
 
-	if res.StatusCode != http.StatusOK {
-		return nil, errors.Errorf("response status code is not OK (%s)", res.Status)
-	}
-
 	doc, err := goquery.NewDocumentFromReader(res.Body)
 	if err != nil {
 		return nil, errors.Wrap(err, "get goquery document from response")
@@ -71,6 +63,7 @@ func (p *Blog) GetPosts(ctx context.Context) ([]entity.Post, error) {
 		linkElement := blogtitle.Find("a")
 		if linkElement.Length() == 0 {
 			p.log.Error("no link element")
+			metrics.ParseErrors.WithLabelValues(parserHTML, "title").Inc()
 			return
 		}
 
@@ -79,18 +72,21 @@ func (p *Blog) GetPosts(ctx context.Context) ([]entity.Post, error) {
 		dateElement := linkElement.Next()
 		if dateElement.Length() == 0 {
 			p.log.Error("no next element after link")
+			metrics.ParseErrors.WithLabelValues(parserHTML, "date").Inc()
 			return
 		}
 
 		date, err := time.Parse(dateLayout, strings.TrimSpace(dateElement.Text()))
 		if err != nil {
 			p.log.Error("can't parse date")
+			metrics.ParseErrors.WithLabelValues(parserHTML, "date").Inc()
 			return
 		}
 
 		authorElement := blogtitle.Find(".author")
 		if authorElement.Length() == 0 {
 			p.log.Error("no author element")
+			metrics.ParseErrors.WithLabelValues(parserHTML, "author").Inc()
 			return
 		}
 
@@ -99,6 +95,7 @@ func (p *Blog) GetPosts(ctx context.Context) ([]entity.Post, error) {
 		summaryElement := blogtitle.Next()
 		if summaryElement.Length() == 0 {
 			p.log.Error("no next element after blogtitle")
+			metrics.ParseErrors.WithLabelValues(parserHTML, "summary").Inc()
 			return
 		}
 
@@ -107,6 +104,7 @@ func (p *Blog) GetPosts(ctx context.Context) ([]entity.Post, error) {
 		path, ok := linkElement.Attr("href")
 		if !ok {
 			p.log.Error("no href attribute on link element")
+			metrics.ParseErrors.WithLabelValues(parserHTML, "url").Inc()
 			return
 		}
 		url := fmt.Sprintf("%s://%s%s", p.protocol, p.host, path)
@@ -117,6 +115,7 @@ func (p *Blog) GetPosts(ctx context.Context) ([]entity.Post, error) {
 			Author:  author,
 			Summary: summary,
 			URL:     url,
+			Updated: date, // HTML page doesn't show date of post's last update.
 		})
 	})
 
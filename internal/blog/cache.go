@@ -0,0 +1,144 @@
+package blog
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"gbu-scanner/internal/entity"
+	"gbu-scanner/internal/metrics"
+	"gbu-scanner/internal/scanner"
+
+	"gbu-scanner/pkg/logger"
+
+	"github.com/pkg/errors"
+)
+
+// CachingClient is HTTPClient which makes conditional GET requests with validators
+// (ETag and Last-Modified) of last committed response. It's also scanner.ResponseCache:
+// validators of new responses are saved to CacheStore only with Commit.
+type CachingClient struct {
+	client HTTPClient
+	store  CacheStore
+	log    logger.Logger
+
+	// committed are validators used in requests by url, loaded from store on first request.
+	committed map[string]entity.CacheValidators
+	// pending are validators of responses got since last commit by url.
+	pending map[string]entity.CacheValidators
+	mu      *sync.Mutex // Protects committed and pending.
+}
+
+var (
+	_ HTTPClient            = &CachingClient{}
+	_ scanner.ResponseCache = &CachingClient{}
+)
+
+// NewCachingClient returns HTTPClient which makes conditional requests with client.
+func NewCachingClient(client HTTPClient, store CacheStore, log logger.Logger) *CachingClient {
+	return &CachingClient{
+		client: client,
+		store:  store,
+		log:    log,
+
+		committed: make(map[string]entity.CacheValidators),
+		pending:   make(map[string]entity.CacheValidators),
+		mu:        &sync.Mutex{},
+	}
+}
+
+// Do executes request, GET requests are made conditional if there are validators for request's url.
+// Response with NotModified status is returned as is, fetch converts it to scanner.ErrNotModified.
+func (c *CachingClient) Do(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return c.client.Do(req)
+	}
+
+	url := req.URL.String()
+
+	validators, err := c.getCommitted(req.Context(), url)
+	if err != nil {
+		// Request without validators is still fine, it's just not conditional.
+		c.log.Warn(errors.Wrapf(err, "can't get cache validators of %q", url))
+	}
+
+	if validators.ETag != "" {
+		req.Header.Set("If-None-Match", validators.ETag)
+	}
+	if validators.LastModified != "" {
+		req.Header.Set("If-Modified-Since", validators.LastModified)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch res.StatusCode {
+	case http.StatusNotModified:
+		metrics.CacheResponses.WithLabelValues(url, "not_modified").Inc()
+		c.log.Debugf("%q not modified (etag %q, last modified %q)", url, validators.ETag, validators.LastModified)
+	case http.StatusOK:
+		metrics.CacheResponses.WithLabelValues(url, "modified").Inc()
+
+		newValidators := entity.CacheValidators{
+			ETag:         res.Header.Get("ETag"),
+			LastModified: res.Header.Get("Last-Modified"),
+		}
+		if newValidators != validators {
+			c.log.Debugf("%q modified (etag %q, last modified %q)", url, newValidators.ETag, newValidators.LastModified)
+		}
+
+		c.mu.Lock()
+		c.pending[url] = newValidators
+		c.mu.Unlock()
+	}
+
+	return res, nil
+}
+
+// Commit saves validators of responses got since last commit to store,
+// next requests to their urls are made with these validators.
+func (c *CachingClient) Commit(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for url, validators := range c.pending {
+		if validators == c.committed[url] {
+			delete(c.pending, url)
+			continue
+		}
+
+		err := c.store.Set(ctx, url, validators)
+		if err != nil {
+			return errors.Wrapf(err, "save cache validators of %q", url)
+		}
+
+		c.committed[url] = validators
+		delete(c.pending, url)
+
+		c.log.Infof("cache validators of %q committed (etag %q, last modified %q)",
+			url, validators.ETag, validators.LastModified)
+	}
+
+	return nil
+}
+
+// getCommitted returns committed validators of url, loading them from store on first call.
+func (c *CachingClient) getCommitted(ctx context.Context, url string) (entity.CacheValidators, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if validators, ok := c.committed[url]; ok {
+		return validators, nil
+	}
+
+	validators, err := c.store.Get(ctx, url)
+	if err != nil {
+		return entity.CacheValidators{}, errors.Wrap(err, "get validators from store")
+	}
+
+	c.committed[url] = validators
+
+	return validators, nil
+}
@@ -1,4 +1,15 @@
 package blog
 
+import "time"
+
 // dateLayout is layout for parsing date from go.dev's blog with time.Parse function.
 const dateLayout = "_2 January 2006"
+
+// Parsers' names for metrics' labels.
+const (
+	parserHTML = "html"
+	parserAtom = "atom"
+)
+
+// atomDateLayout is layout for parsing dates from Atom feed (RFC 3339 by RFC 4287).
+const atomDateLayout = time.RFC3339
@@ -1,3 +1,4 @@
-// Package blog provides implementation for scanner.Blog interface -
-// it fetches posts from blog (go.dev/blog)
+// Package blog provides implementations for scanner.Blog interface -
+// they fetch posts from blog (go.dev/blog) either by scraping HTML page
+// with all posts (Blog) or by parsing Atom feed (Atom).
 package blog
@@ -0,0 +1,37 @@
+package blog
+
+import (
+	"context"
+	"net/http"
+
+	"gbu-scanner/internal/scanner"
+
+	"github.com/pkg/errors"
+)
+
+// fetch executes GET request to url and returns response if it's status code is OK.
+// If response's status code is NotModified (client is CachingClient), scanner.ErrNotModified returned.
+// Caller is responsible for closing response's body.
+func fetch(ctx context.Context, client HTTPClient, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "create request")
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "execute request")
+	}
+
+	if res.StatusCode == http.StatusNotModified {
+		res.Body.Close()
+		return nil, scanner.ErrNotModified
+	}
+
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, errors.Errorf("response status code is not OK (%s)", res.Status)
+	}
+
+	return res, nil
+}
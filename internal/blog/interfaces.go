@@ -1,6 +1,11 @@
 package blog
 
-import "net/http"
+import (
+	"context"
+	"net/http"
+
+	"gbu-scanner/internal/entity"
+)
 
 // HTTPClient is interface for executing http requests.
 // http.DefaultClient implements it.
@@ -8,3 +13,12 @@ type HTTPClient interface {
 	// Do executes http request and retruns response
 	Do(*http.Request) (*http.Response, error)
 }
+
+// CacheStore is interface for persisting http cache validators of blog's pages,
+// so conditional requests work after restart.
+type CacheStore interface {
+	// Get returns validators saved for url. Empty validators returned if nothing saved.
+	Get(ctx context.Context, url string) (entity.CacheValidators, error)
+	// Set saves validators for url.
+	Set(ctx context.Context, url string, validators entity.CacheValidators) error
+}
@@ -0,0 +1,140 @@
+package blog
+
+import (
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gbu-scanner/internal/metrics"
+
+	"gbu-scanner/pkg/logger"
+	"gbu-scanner/pkg/sleep"
+
+	"github.com/pkg/errors"
+)
+
+// RetryPolicy is configuration of retries of failed requests.
+type RetryPolicy struct {
+	// MaxAttempts is max count of attempts including first one.
+	MaxAttempts int
+	// BaseDelay is delay before first retry, each next delay is doubled.
+	BaseDelay time.Duration
+	// MaxDelay caps delay between attempts. If server asks (with Retry-After header)
+	// to wait longer than MaxDelay, request is not retried.
+	MaxDelay time.Duration
+	// Jitter is fraction (from 0 to 1) of delay which is randomized,
+	// so replicas don't retry simultaneously.
+	Jitter float64
+	// RetryableStatusCodes are response's status codes which are retried.
+	RetryableStatusCodes []int
+}
+
+// RetryingClient is HTTPClient which retries failed requests with exponential backoff.
+// Request is retried on network error or response with retryable status code.
+type RetryingClient struct {
+	client HTTPClient
+	policy RetryPolicy
+	log    logger.Logger
+}
+
+var _ HTTPClient = &RetryingClient{}
+
+// NewRetryingClient returns HTTPClient which retries requests with client according to policy.
+func NewRetryingClient(client HTTPClient, policy RetryPolicy, log logger.Logger) *RetryingClient {
+	return &RetryingClient{
+		client: client,
+		policy: policy,
+		log:    log,
+	}
+}
+
+// Do executes request with retries. Response of last attempt is returned. Requests
+// with body are not retried as body can't be read twice.
+func (c *RetryingClient) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	for attempt := 1; ; attempt++ {
+		res, err := c.client.Do(req.Clone(ctx))
+		if attempt >= c.policy.MaxAttempts || req.Body != nil || ctx.Err() != nil {
+			return res, err
+		}
+
+		var retryAfter time.Duration
+		if err == nil {
+			if !c.isRetryable(res.StatusCode) {
+				return res, nil
+			}
+
+			retryAfter = parseRetryAfter(res.Header.Get("Retry-After"))
+			if retryAfter > c.policy.MaxDelay {
+				return res, nil
+			}
+
+			// Body is drained to reuse connection.
+			_, _ = io.Copy(ioutil.Discard, res.Body)
+			res.Body.Close()
+
+			err = errors.Errorf("response status code is %s", res.Status)
+		}
+
+		delay := c.delay(attempt)
+		if retryAfter > delay {
+			delay = retryAfter
+		}
+
+		metrics.FetchRetries.WithLabelValues(req.URL.String()).Inc()
+		c.log.Warn(errors.Wrapf(err, "request to %q failed (attempt #%d), retrying in %s", req.URL, attempt, delay))
+
+		isCtxClosed := sleep.WithContext(ctx, delay)
+		if isCtxClosed {
+			return nil, errors.Wrap(ctx.Err(), "wait before retry")
+		}
+	}
+}
+
+// isRetryable returns true if response with statusCode should be retried.
+func (c *RetryingClient) isRetryable(statusCode int) bool {
+	for _, code := range c.policy.RetryableStatusCodes {
+		if code == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// delay returns delay before retry after failed attempt: BaseDelay doubled on each
+// attempt, capped with MaxDelay and randomly reduced by up to Jitter fraction.
+func (c *RetryingClient) delay(attempt int) time.Duration {
+	delay := float64(c.policy.BaseDelay) * math.Pow(2, float64(attempt-1))
+	if delay > float64(c.policy.MaxDelay) {
+		delay = float64(c.policy.MaxDelay)
+	}
+
+	delay -= delay * c.policy.Jitter * rand.Float64() //nolint:gosec // Jitter doesn't need crypto rand.
+
+	return time.Duration(delay)
+}
+
+// parseRetryAfter parses Retry-After header's value which is either
+// delay in seconds or http date. Zero returned if value is empty or invalid.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+
+	return 0
+}
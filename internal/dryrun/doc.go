@@ -0,0 +1,4 @@
+// Package dryrun provides implementations for scanner.Posts, scanner.Outbox and scanner.Publisher
+// interfaces for dry run: published posts are read from real storage, but nothing is written to
+// it or published, writes are kept in memory and logged instead.
+package dryrun
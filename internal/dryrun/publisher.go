@@ -0,0 +1,39 @@
+package dryrun
+
+import (
+	"context"
+	"encoding/json"
+
+	"gbu-scanner/internal/entity"
+	"gbu-scanner/internal/scanner"
+
+	"gbu-scanner/pkg/logger"
+
+	"github.com/pkg/errors"
+)
+
+// Publisher is implementation for scanner.Publisher interface for dry run,
+// it logs events instead of publishing them.
+type Publisher struct {
+	log logger.Logger
+}
+
+var _ scanner.Publisher = &Publisher{}
+
+// NewPublisher returns scanner.Publisher implementation which only logs events.
+func NewPublisher(log logger.Logger) *Publisher {
+	return &Publisher{
+		log: log,
+	}
+}
+
+func (p *Publisher) Publish(_ context.Context, event entity.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "encode event")
+	}
+
+	p.log.Infof("dry run: publishing %s event of post %q: %s", event.Type, event.Post.URL, body)
+
+	return nil
+}
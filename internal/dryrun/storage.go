@@ -0,0 +1,214 @@
+package dryrun
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gbu-scanner/internal/entity"
+	"gbu-scanner/internal/scanner"
+
+	"gbu-scanner/pkg/logger"
+
+	"github.com/pkg/errors"
+)
+
+// Storage is implementation for scanner.Posts and scanner.Outbox interfaces for dry run.
+// It reads published posts from real storage and overlays them with posts "saved" during dry run,
+// so same post is reported once. Outbox is kept in memory only. Each transaction's events are
+// logged as report of what would be published.
+type Storage struct {
+	posts scanner.Posts
+	log   logger.Logger
+
+	saved   map[string]entity.Post // Saved posts by url.
+	pending []entity.OutboxEntry
+	lastID  int
+	mu      *sync.Mutex // Protects saved, pending and lastID.
+}
+
+var (
+	_ scanner.Posts  = &Storage{}
+	_ scanner.Outbox = &Storage{}
+)
+
+// New returns scanner.Posts and scanner.Outbox implementation which reads published posts from posts.
+func New(posts scanner.Posts, log logger.Logger) *Storage {
+	return &Storage{
+		posts: posts,
+		log:   log,
+
+		saved: make(map[string]entity.Post),
+		mu:    &sync.Mutex{},
+	}
+}
+
+// txKey is context's key of transaction.
+type txKey struct{}
+
+// transaction is writes made in transaction, they are applied only if transaction succeeds.
+type transaction struct {
+	posts  []entity.Post
+	events []entity.Event
+}
+
+// Transaction calls fn and applies writes made with txCtx if fn succeeds. Events enqueued
+// in transaction are logged as report.
+func (s *Storage) Transaction(ctx context.Context, fn func(txCtx context.Context) error) error {
+	tx := &transaction{}
+
+	err := fn(context.WithValue(ctx, txKey{}, tx))
+	if err != nil {
+		return err
+	}
+
+	s.apply(tx)
+
+	if len(tx.events) != 0 {
+		s.log.Info(report(tx.events))
+	}
+
+	return nil
+}
+
+func (s *Storage) Add(ctx context.Context, post entity.Post) error {
+	s.log.Debugf("dry run: adding post %q to published posts", post.URL)
+	s.write(ctx, transaction{posts: []entity.Post{post}})
+	return nil
+}
+
+func (s *Storage) Update(ctx context.Context, post entity.Post) error {
+	s.log.Debugf("dry run: updating published post %q to revision %d", post.URL, post.Revision)
+	s.write(ctx, transaction{posts: []entity.Post{post}})
+	return nil
+}
+
+// GetAll returns posts from real storage overlaid with posts saved during dry run.
+func (s *Storage) GetAll(ctx context.Context) ([]entity.Post, error) {
+	posts, err := s.posts.GetAll(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "get published posts")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	isOverlaid := make(map[string]bool, len(s.saved))
+	for i, p := range posts {
+		if saved, ok := s.saved[p.URL]; ok {
+			posts[i] = saved
+			isOverlaid[p.URL] = true
+		}
+	}
+
+	for url, saved := range s.saved {
+		if !isOverlaid[url] {
+			posts = append(posts, saved)
+		}
+	}
+
+	return posts, nil
+}
+
+func (s *Storage) Enqueue(ctx context.Context, event entity.Event) error {
+	s.write(ctx, transaction{events: []entity.Event{event}})
+	return nil
+}
+
+func (s *Storage) GetPending(_ context.Context, limit int) ([]entity.OutboxEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if limit > len(s.pending) {
+		limit = len(s.pending)
+	}
+
+	return append([]entity.OutboxEntry(nil), s.pending[:limit]...), nil
+}
+
+func (s *Storage) MarkSent(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, entry := range s.pending {
+		if entry.ID == id {
+			s.pending = append(s.pending[:i], s.pending[i+1:]...)
+			return nil
+		}
+	}
+
+	return errors.Errorf("entry %q not found", id)
+}
+
+func (s *Storage) MarkFailed(_ context.Context, id string, reason error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.pending {
+		if s.pending[i].ID == id {
+			s.pending[i].Attempts++
+			s.pending[i].LastError = reason.Error()
+			return nil
+		}
+	}
+
+	return errors.Errorf("entry %q not found", id)
+}
+
+func (s *Storage) CountPending(_ context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return int64(len(s.pending)), nil
+}
+
+// write adds writes to ctx's transaction or applies them if there is no transaction.
+func (s *Storage) write(ctx context.Context, writes transaction) {
+	tx, ok := ctx.Value(txKey{}).(*transaction)
+	if !ok {
+		s.apply(&writes)
+		return
+	}
+
+	tx.posts = append(tx.posts, writes.posts...)
+	tx.events = append(tx.events, writes.events...)
+}
+
+// apply saves transaction's posts and enqueues it's events.
+func (s *Storage) apply(tx *transaction) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, p := range tx.posts {
+		s.saved[p.URL] = p
+	}
+
+	for _, event := range tx.events {
+		s.lastID++
+		event.ID = strconv.Itoa(s.lastID)
+		s.pending = append(s.pending, entity.OutboxEntry{
+			ID:        event.ID,
+			Event:     event,
+			CreatedAt: time.Now(),
+		})
+	}
+}
+
+// report returns human-readable report of events which would be published.
+func report(events []entity.Event) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "dry run: %d events would be published:", len(events))
+
+	for _, event := range events {
+		p := event.Post
+		fmt.Fprintf(&b, "\n  %s %q (%s) from %q, revision %d", event.Type, p.Title, p.URL, p.Source, p.Revision)
+		for _, change := range event.Diff {
+			fmt.Fprintf(&b, "\n    %s: %q -> %q", change.Field, change.Old, change.New)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,8 @@
+package entity
+
+// CacheValidators are http cache validators of response used in
+// conditional requests (If-None-Match and If-Modified-Since headers).
+type CacheValidators struct {
+	ETag         string `bson:"etag"`
+	LastModified string `bson:"lastModified"`
+}
@@ -0,0 +1,37 @@
+package entity
+
+import "time"
+
+// EventType is type of event about post published to message broker.
+type EventType string
+
+const (
+	// EventPostCreated is type of event about new post in blog.
+	EventPostCreated EventType = "post.created"
+	// EventPostUpdated is type of event about edited post which was already published.
+	EventPostUpdated EventType = "post.updated"
+	// EventPostRemoved is type of event about published post which disappeared from blog.
+	EventPostRemoved EventType = "post.removed"
+)
+
+// Event is event about post to publish to message broker.
+type Event struct {
+	// ID is unique event's identifier, it's the same for each publishing attempt of event.
+	// It's assigned by outbox, so it's not stored inside event. Empty for events not from outbox.
+	ID   string    `json:"id,omitempty" bson:"-"`
+	Type EventType `json:"type" bson:"type"`
+	// OccurredAt is time when event was detected by scanner.
+	OccurredAt time.Time `json:"occurredAt" bson:"occurredAt"`
+	Post       Post      `json:"post" bson:"post"`
+	// Diff is list of post's changed fields. Only for EventPostUpdated.
+	Diff []FieldChange `json:"diff,omitempty" bson:"diff,omitempty"`
+	// Replay is true if event is republished by replay (backfill), not detected by scanner.
+	Replay bool `json:"replay,omitempty" bson:"replay,omitempty"`
+}
+
+// FieldChange is change of one post's field.
+type FieldChange struct {
+	Field string `json:"field" bson:"field"`
+	Old   string `json:"old" bson:"old"`
+	New   string `json:"new" bson:"new"`
+}
@@ -0,0 +1,17 @@
+package entity
+
+import "time"
+
+// OutboxEntry is an event waiting in outbox to be published to message broker.
+type OutboxEntry struct {
+	// ID is entry's identifier in outbox.
+	ID string
+	// Event is event to publish.
+	Event Event
+	// CreatedAt is time when entry was enqueued.
+	CreatedAt time.Time
+	// Attempts is count of failed publishing attempts.
+	Attempts int
+	// LastError is error of last failed publishing attempt.
+	LastError string
+}
@@ -2,12 +2,67 @@ package entity
 
 import "time"
 
-// Post is a short data about post in blog, without content.
-// Content is available at the URL.
+// Post is a short data about post in blog. Content is available at the URL
+// and is fetched only if enrichment is enabled.
 type Post struct {
+	// ID is unique identifier of post given by blog's source.
+	// Empty if source doesn't provide identifiers (HTML page).
+	ID      string    `json:"id,omitempty" bson:"id,omitempty"`
 	Title   string    `json:"title" bson:"title"`
 	Date    time.Time `json:"date" bson:"date"`
+	Updated time.Time `json:"updated" bson:"updated"`
 	Author  string    `json:"author" bson:"author"`
 	Summary string    `json:"summary" bson:"summary"`
 	URL     string    `json:"url" bson:"url"`
+	// Source is name of blog the post fetched from.
+	Source string `json:"source" bson:"source"`
+	// Revision is incremented each time edit of post is detected, first revision is 1.
+	// Posts stored before revisions were introduced have 0 revision.
+	Revision int `json:"revision" bson:"revision"`
+	// Removed is true if post disappeared from blog.
+	Removed bool `json:"removed,omitempty" bson:"removed,omitempty"`
+	// Content is article's content fetched from post's page. Nil if enrichment is disabled or failed.
+	Content *PostContent `json:"content,omitempty" bson:"content,omitempty"`
+}
+
+// PostContent is post's article content.
+type PostContent struct {
+	// HTML is article's HTML without title and authors, urls are absolute.
+	HTML string `json:"html" bson:"html"`
+	// Markdown is article rendered to Markdown (readable as plain text).
+	Markdown string `json:"markdown" bson:"markdown"`
+	// ReadingMinutes is estimated time to read article.
+	ReadingMinutes int `json:"readingMinutes" bson:"readingMinutes"`
+	// Headings are article's headings' texts in order of appearance.
+	Headings []string `json:"headings" bson:"headings"`
+	// Tags are post's tags, empty if blog doesn't show them.
+	Tags []string `json:"tags" bson:"tags"`
+}
+
+// Diff returns changes of post's content fields (title, date, author and summary)
+// from p to other. Empty diff means posts are same.
+func (p Post) Diff(other Post) []FieldChange {
+	var diff []FieldChange
+
+	if p.Title != other.Title {
+		diff = append(diff, FieldChange{Field: "title", Old: p.Title, New: other.Title})
+	}
+
+	if !p.Date.Equal(other.Date) {
+		diff = append(diff, FieldChange{
+			Field: "date",
+			Old:   p.Date.Format(time.RFC3339),
+			New:   other.Date.Format(time.RFC3339),
+		})
+	}
+
+	if p.Author != other.Author {
+		diff = append(diff, FieldChange{Field: "author", Old: p.Author, New: other.Author})
+	}
+
+	if p.Summary != other.Summary {
+		diff = append(diff, FieldChange{Field: "summary", Old: p.Summary, New: other.Summary})
+	}
+
+	return diff
 }
@@ -0,0 +1,4 @@
+package httpcache
+
+// httpCacheCollection is name of collection in mongodb with cache validators.
+const httpCacheCollection = "httpCache"
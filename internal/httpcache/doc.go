@@ -0,0 +1,3 @@
+// Package httpcache provides implementation for blog.CacheStore interface -
+// it stores http cache validators of blog's pages in mongodb.
+package httpcache
@@ -0,0 +1,87 @@
+package httpcache
+
+import (
+	"context"
+	"time"
+
+	"gbu-scanner/internal/blog"
+	"gbu-scanner/internal/entity"
+
+	"gbu-scanner/pkg/logger"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Store is implementation for blog.CacheStore interface.
+type Store struct {
+	mongoDB *mongo.Database
+	log     logger.Logger
+}
+
+var _ blog.CacheStore = &Store{}
+
+// New returns blog.CacheStore implementation.
+func New(mongo *mongo.Client, database string, log logger.Logger) *Store {
+	return &Store{
+		mongoDB: mongo.Database(database),
+		log:     log,
+	}
+}
+
+// document is cache validators' document in mongodb.
+type document struct {
+	URL        string                 `bson:"url"`
+	Validators entity.CacheValidators `bson:"validators"`
+	UpdatedAt  time.Time              `bson:"updatedAt"`
+}
+
+// Init creates unique index on url if it doesn't exist.
+func (s *Store) Init(ctx context.Context) error {
+	_, err := s.mongoDB.Collection(httpCacheCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "url", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return errors.Wrap(err, "create unique index on url")
+	}
+
+	return nil
+}
+
+func (s *Store) Get(ctx context.Context, url string) (entity.CacheValidators, error) {
+	res := s.mongoDB.Collection(httpCacheCollection).FindOne(ctx, bson.D{{Key: "url", Value: url}})
+	if errors.Is(res.Err(), mongo.ErrNoDocuments) {
+		return entity.CacheValidators{}, nil
+	}
+	if res.Err() != nil {
+		return entity.CacheValidators{}, errors.Wrap(res.Err(), "find document")
+	}
+
+	var doc document
+	err := res.Decode(&doc)
+	if err != nil {
+		return entity.CacheValidators{}, errors.Wrap(err, "decode document")
+	}
+
+	return doc.Validators, nil
+}
+
+func (s *Store) Set(ctx context.Context, url string, validators entity.CacheValidators) error {
+	_, err := s.mongoDB.Collection(httpCacheCollection).ReplaceOne(ctx,
+		bson.D{{Key: "url", Value: url}},
+		document{
+			URL:        url,
+			Validators: validators,
+			UpdatedAt:  time.Now(),
+		},
+		options.Replace().SetUpsert(true),
+	)
+	if err != nil {
+		return errors.Wrap(err, "upsert document")
+	}
+
+	return nil
+}
@@ -0,0 +1,7 @@
+package leader
+
+// leasesCollection is name of collection in mongodb with leases.
+const leasesCollection = "leases"
+
+// renewsPerTTL is count of lease's renewals (and acquire attempts of followers) per lease's TTL.
+const renewsPerTTL = 3
@@ -0,0 +1,4 @@
+// Package leader provides leader election between service's replicas
+// based on lease document in mongodb. Only leader does the work, so
+// several replicas don't publish same posts twice.
+package leader
@@ -0,0 +1,202 @@
+package leader
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gbu-scanner/internal/metrics"
+
+	"gbu-scanner/pkg/logger"
+	"gbu-scanner/pkg/sleep"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Elector is struct that elects leader between replicas with lease document in mongodb.
+// Replica holding not expired lease is leader, it renews lease every TTL/3. If leader dies,
+// lease expires and one of followers acquires it within TTL/3. Replicas' clocks must be
+// synchronized with precision much better than TTL.
+type Elector struct {
+	mongoDB *mongo.Database
+	name    string
+	id      string
+	ttl     time.Duration
+	log     logger.Logger
+
+	isLeader bool
+	mu       *sync.RWMutex // Protects isLeader.
+}
+
+// New returns elector of leader for lease with name. id must be unique for each replica.
+func New(mongo *mongo.Client, database string, name, id string, ttl time.Duration, log logger.Logger) *Elector {
+	return &Elector{
+		mongoDB: mongo.Database(database),
+		name:    name,
+		id:      id,
+		ttl:     ttl,
+		log:     log,
+
+		mu: &sync.RWMutex{},
+	}
+}
+
+// lease is lease's document in mongodb.
+type lease struct {
+	Name      string    `bson:"_id"`
+	Holder    string    `bson:"holder"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+	RenewedAt time.Time `bson:"renewedAt"`
+}
+
+// IsLeader returns true if this replica is leader now.
+func (e *Elector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.isLeader
+}
+
+// Run is a blocking method until context cancelled or fn returns error. It waits for leadership
+// and calls fn while replica is leader. fn's context is cancelled when leadership is lost
+// (fn is called again after leadership is acquired again) or ctx is closed.
+// Lease is released when fn returns, so other replica takes over without waiting for expiration.
+// Run returns fn's error or nil-error when context is closed.
+func (e *Elector) Run(ctx context.Context, fn func(ctx context.Context) error) error {
+	e.log.Infof("starting leader election as %q", e.id)
+
+	interval := e.ttl / renewsPerTTL
+	for isCtxClosed := false; !isCtxClosed; isCtxClosed = sleep.WithContext(ctx, interval) {
+		acquired, err := e.tryAcquire(ctx)
+		if err != nil {
+			e.log.Error(errors.Wrap(err, "can't acquire lease"))
+			continue
+		}
+
+		if !acquired {
+			continue
+		}
+
+		err = e.lead(ctx, fn)
+		if err != nil {
+			return err
+		}
+	}
+
+	e.log.Info("leader election finished")
+
+	return nil
+}
+
+// lead calls fn and renews lease until fn returns or leadership is lost.
+func (e *Elector) lead(ctx context.Context, fn func(ctx context.Context) error) error {
+	e.setLeader(true)
+	defer e.setLeader(false)
+
+	e.log.Infof("became leader of %q", e.name)
+
+	leaderCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- fn(leaderCtx)
+	}()
+
+	interval := e.ttl / renewsPerTTL
+	lastRenew := time.Now()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case err := <-errs:
+			e.release()
+			return err
+		case <-ticker.C:
+		}
+
+		renewed, err := e.tryAcquire(leaderCtx)
+		if err != nil {
+			e.log.Error(errors.Wrap(err, "can't renew lease"))
+		}
+		if renewed {
+			lastRenew = time.Now()
+			continue
+		}
+
+		// Lease is taken by other replica or can't be renewed long enough so it may be taken
+		// soon - stepping down before other replica becomes leader.
+		if err == nil || time.Since(lastRenew) >= e.ttl-interval {
+			e.log.Warnf("lost leadership of %q", e.name)
+			cancel()
+			return <-errs
+		}
+	}
+}
+
+// tryAcquire acquires or renews lease. Returns true if lease is held by this replica.
+func (e *Elector) tryAcquire(ctx context.Context) (bool, error) {
+	now := time.Now()
+
+	// Lease is upserted only if it's held by this replica or expired. Otherwise
+	// filter doesn't match existing document and upsert fails with duplicate key.
+	_, err := e.mongoDB.Collection(leasesCollection).UpdateOne(ctx,
+		bson.D{
+			{Key: "_id", Value: e.name},
+			{Key: "$or", Value: bson.A{
+				bson.D{{Key: "holder", Value: e.id}},
+				bson.D{{Key: "expiresAt", Value: bson.D{{Key: "$lt", Value: now}}}},
+			}},
+		},
+		bson.D{{Key: "$set", Value: lease{
+			Name:      e.name,
+			Holder:    e.id,
+			ExpiresAt: now.Add(e.ttl),
+			RenewedAt: now,
+		}}},
+		options.Update().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "upsert lease")
+	}
+
+	return true, nil
+}
+
+// release expires lease if it's held by this replica.
+func (e *Elector) release() {
+	// Context could be closed already, but lease should be released anyway.
+	ctx, cancel := context.WithTimeout(context.Background(), e.ttl/renewsPerTTL)
+	defer cancel()
+
+	_, err := e.mongoDB.Collection(leasesCollection).UpdateOne(ctx,
+		bson.D{{Key: "_id", Value: e.name}, {Key: "holder", Value: e.id}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "expiresAt", Value: time.Now()}}}},
+	)
+	if err != nil {
+		e.log.Error(errors.Wrap(err, "can't release lease"))
+		return
+	}
+
+	e.log.Infof("released lease of %q", e.name)
+}
+
+// setLeader sets isLeader flag and metric.
+func (e *Elector) setLeader(isLeader bool) {
+	e.mu.Lock()
+	e.isLeader = isLeader
+	e.mu.Unlock()
+
+	if isLeader {
+		metrics.IsLeader.Set(1)
+	} else {
+		metrics.IsLeader.Set(0)
+	}
+}
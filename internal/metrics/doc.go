@@ -0,0 +1,3 @@
+// Package metrics provides service's prometheus metrics. Metrics are registered
+// in default prometheus registry and exposed by admin server at /metrics.
+package metrics
@@ -0,0 +1,219 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace is prefix of all service's metrics.
+const namespace = "gbu_scanner"
+
+// Label values for ScanIterations' result label and others.
+const (
+	ResultSuccess = "success"
+	ResultError   = "error"
+)
+
+// Scanner's metrics.
+var (
+	// ScanDuration is duration of scan iterations by source.
+	ScanDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "scanner",
+		Name:      "scan_duration_seconds",
+		Help:      "Duration of scan iterations.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"source"})
+	// ScanIterations is count of scan iterations by source and result (success or error).
+	ScanIterations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "scanner",
+		Name:      "scan_iterations_total",
+		Help:      "Count of scan iterations by result.",
+	}, []string{"source", "result"})
+	// NewPosts is count of new posts found in blogs and enqueued to outbox.
+	NewPosts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "scanner",
+		Name:      "new_posts_total",
+		Help:      "Count of new posts enqueued to outbox.",
+	}, []string{"source"})
+	// UpdatedPosts is count of edited posts found in blogs and enqueued to outbox.
+	UpdatedPosts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "scanner",
+		Name:      "updated_posts_total",
+		Help:      "Count of edited posts enqueued to outbox.",
+	}, []string{"source"})
+	// RemovedPosts is count of posts disappeared from blogs and enqueued to outbox.
+	RemovedPosts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "scanner",
+		Name:      "removed_posts_total",
+		Help:      "Count of removed posts enqueued to outbox.",
+	}, []string{"source"})
+	// SeededPosts is count of posts saved as published without publishing on first scan by source.
+	SeededPosts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "scanner",
+		Name:      "seeded_posts_total",
+		Help:      "Count of posts saved as published without publishing on first scan of blog.",
+	}, []string{"source"})
+	// OutboxPending is count of pending posts in outbox seen by relay.
+	OutboxPending = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "relay",
+		Name:      "outbox_pending",
+		Help:      "Count of posts waiting in outbox to be published.",
+	})
+)
+
+// Leader election's metrics.
+var (
+	// IsLeader is 1 if replica is leader and 0 otherwise.
+	IsLeader = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "leader",
+		Name:      "is_leader",
+		Help:      "1 if replica is leader (scans blogs and publishes posts), 0 otherwise.",
+	})
+)
+
+// Blog's metrics.
+var (
+	// FetchDuration is duration of getting posts from blog by source.
+	FetchDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "blog",
+		Name:      "fetch_duration_seconds",
+		Help:      "Duration of getting posts from blog.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"source"})
+	// ParsedPosts is count of posts parsed from blog in last scan iteration by source.
+	ParsedPosts = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "blog",
+		Name:      "parsed_posts",
+		Help:      "Count of posts parsed from blog in last scan iteration.",
+	}, []string{"source"})
+	// EnrichFailures is count of new posts which content can't be fetched by source.
+	EnrichFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "blog",
+		Name:      "enrich_failures_total",
+		Help:      "Count of new posts which content can't be fetched.",
+	}, []string{"source"})
+	// FetchRetries is count of retried requests to blog by url.
+	FetchRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "blog",
+		Name:      "fetch_retries_total",
+		Help:      "Count of retried requests to blog.",
+	}, []string{"url"})
+	// CacheResponses is count of responses to conditional requests by url and result (modified or not_modified).
+	CacheResponses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "blog",
+		Name:      "cache_responses_total",
+		Help:      "Count of responses to conditional requests to blog.",
+	}, []string{"url", "result"})
+	// ParseErrors is count of posts skipped because of parsing error by parser (html or atom) and field.
+	ParseErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "blog",
+		Name:      "parse_errors_total",
+		Help:      "Count of posts skipped because field can't be parsed.",
+	}, []string{"parser", "field"})
+)
+
+// Storage's metrics.
+var (
+	// StorageFailures is count of failed storage operations by operation.
+	StorageFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "posts",
+		Name:      "storage_failures_total",
+		Help:      "Count of failed storage operations.",
+	}, []string{"operation"})
+)
+
+// Publisher's metrics.
+var (
+	// Published is count of posts published and confirmed by broker.
+	Published = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "publisher",
+		Name:      "published_total",
+		Help:      "Count of posts published and confirmed by broker.",
+	})
+	// PublishFailures is count of failed publishes by reason.
+	PublishFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "publisher",
+		Name:      "publish_failures_total",
+		Help:      "Count of failed publishes.",
+	}, []string{"reason"})
+	// Unroutable is count of messages returned by broker as unroutable and
+	// dropped (treated as published) with RabbitConfig.DropUnroutable.
+	Unroutable = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "publisher",
+		Name:      "unroutable_total",
+		Help:      "Count of messages returned by broker as unroutable and dropped.",
+	})
+	// ReconnectAttempts is count of attempts to reconnect to broker by result (success or error).
+	ReconnectAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "publisher",
+		Name:      "reconnect_attempts_total",
+		Help:      "Count of attempts to reconnect to broker.",
+	}, []string{"result"})
+	// Connected is 1 while connection and channel to broker are open and 0 otherwise.
+	Connected = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "publisher",
+		Name:      "connected",
+		Help:      "1 if connection and channel to broker are open, 0 otherwise.",
+	})
+	// SpooledMessages is count of messages spooled to disk while broker is unavailable.
+	SpooledMessages = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "publisher",
+		Name:      "spooled_messages",
+		Help:      "Count of messages spooled to disk while broker is unavailable.",
+	})
+	// SpoolDropped is count of spooled messages which are dropped as they can't be decoded.
+	SpoolDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "publisher",
+		Name:      "spool_dropped_total",
+		Help:      "Count of spooled messages dropped as they can't be decoded.",
+	})
+)
+
+// Webhook publisher's metrics.
+var (
+	// WebhookDeliveries is count of deliveries of events to webhook's endpoints by result
+	// (success or error). Delivery is failed if all attempts failed.
+	WebhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "webhook",
+		Name:      "deliveries_total",
+		Help:      "Count of deliveries of events to webhook's endpoints by result.",
+	}, []string{"endpoint", "result"})
+	// WebhookRetries is count of retried delivery attempts to webhook's endpoints.
+	WebhookRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "webhook",
+		Name:      "retries_total",
+		Help:      "Count of retried delivery attempts to webhook's endpoints.",
+	}, []string{"endpoint"})
+)
+
+// Handler returns http handler which exposes metrics in prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,4 @@
+package outbox
+
+// outboxCollection is name of collection in mongodb with outbox's entries.
+const outboxCollection = "outbox"
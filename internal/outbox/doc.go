@@ -0,0 +1,4 @@
+// Package outbox provides implementation for scanner.Outbox interface -
+// it stores posts waiting to be published in mongodb, in the same database
+// as package posts, so posts can be enqueued inside posts' transaction.
+package outbox
@@ -0,0 +1,185 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gbu-scanner/internal/entity"
+	"gbu-scanner/internal/scanner"
+
+	"gbu-scanner/pkg/logger"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Outbox is implementation for scanner.Outbox interface.
+type Outbox struct {
+	mongoDB *mongo.Database
+	log     logger.Logger
+}
+
+var _ scanner.Outbox = &Outbox{}
+
+// New returns scanner.Outbox implementation.
+func New(mongo *mongo.Client, database string, log logger.Logger) *Outbox {
+	return &Outbox{
+		mongoDB: mongo.Database(database),
+		log:     log,
+	}
+}
+
+// entry is outbox's document in mongodb.
+type entry struct {
+	ID primitive.ObjectID `bson:"_id,omitempty"`
+	// Key identifies event in outbox to make Enqueue idempotent.
+	Key   string       `bson:"key"`
+	Event entity.Event `bson:"event"`
+	// Post is set instead of Event in entries enqueued before events were introduced,
+	// such entries are treated as EventPostCreated.
+	Post      *entity.Post `bson:"post,omitempty"`
+	CreatedAt time.Time    `bson:"createdAt"`
+	// SentAt is nil while entry is pending.
+	SentAt    *time.Time `bson:"sentAt"`
+	Attempts  int        `bson:"attempts"`
+	LastError string     `bson:"lastError"`
+}
+
+// Init creates outbox's collection with indexes if they don't exist.
+// Collection must exist before transaction: it can't be created inside it.
+func (o *Outbox) Init(ctx context.Context) error {
+	_, err := o.mongoDB.Collection(outboxCollection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "key", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "sentAt", Value: 1}, {Key: "_id", Value: 1}},
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "create indexes")
+	}
+
+	return nil
+}
+
+func (o *Outbox) Enqueue(ctx context.Context, event entity.Event) error {
+	key := eventKey(event)
+	_, err := o.mongoDB.Collection(outboxCollection).UpdateOne(ctx,
+		bson.D{{Key: "key", Value: key}},
+		bson.D{{Key: "$setOnInsert", Value: entry{
+			Key:       key,
+			Event:     event,
+			CreatedAt: time.Now(),
+		}}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return errors.Wrap(err, "upsert entry")
+	}
+
+	return nil
+}
+
+func (o *Outbox) GetPending(ctx context.Context, limit int) ([]entity.OutboxEntry, error) {
+	// ObjectID grows monotonically, so sorting by _id keeps enqueuing order.
+	cur, err := o.mongoDB.Collection(outboxCollection).Find(ctx,
+		bson.D{{Key: "sentAt", Value: nil}},
+		options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(limit)),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "find pending entries")
+	}
+
+	var docs []entry
+	err = cur.All(ctx, &docs)
+	if err != nil {
+		return nil, errors.Wrap(err, "decode pending entries")
+	}
+
+	entries := make([]entity.OutboxEntry, 0, len(docs))
+	for _, doc := range docs {
+		if doc.Post != nil {
+			doc.Event = entity.Event{Type: entity.EventPostCreated, Post: *doc.Post}
+		}
+
+		doc.Event.ID = doc.ID.Hex()
+		// Events enqueued before occurredAt was introduced.
+		if doc.Event.OccurredAt.IsZero() {
+			doc.Event.OccurredAt = doc.CreatedAt
+		}
+
+		entries = append(entries, entity.OutboxEntry{
+			ID:        doc.ID.Hex(),
+			Event:     doc.Event,
+			CreatedAt: doc.CreatedAt,
+			Attempts:  doc.Attempts,
+			LastError: doc.LastError,
+		})
+	}
+
+	return entries, nil
+}
+
+func (o *Outbox) MarkSent(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.Wrap(err, "parse id")
+	}
+
+	_, err = o.mongoDB.Collection(outboxCollection).UpdateOne(ctx,
+		bson.D{{Key: "_id", Value: objectID}},
+		bson.D{{Key: "$set", Value: bson.D{
+			{Key: "sentAt", Value: time.Now()},
+		}}},
+	)
+	if err != nil {
+		return errors.Wrap(err, "update entry")
+	}
+
+	return nil
+}
+
+func (o *Outbox) MarkFailed(ctx context.Context, id string, reason error) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.Wrap(err, "parse id")
+	}
+
+	_, err = o.mongoDB.Collection(outboxCollection).UpdateOne(ctx,
+		bson.D{{Key: "_id", Value: objectID}},
+		bson.D{
+			{Key: "$inc", Value: bson.D{{Key: "attempts", Value: 1}}},
+			{Key: "$set", Value: bson.D{{Key: "lastError", Value: reason.Error()}}},
+		},
+	)
+	if err != nil {
+		return errors.Wrap(err, "update entry")
+	}
+
+	return nil
+}
+
+func (o *Outbox) CountPending(ctx context.Context) (int64, error) {
+	count, err := o.mongoDB.Collection(outboxCollection).CountDocuments(ctx, bson.D{{Key: "sentAt", Value: nil}})
+	if err != nil {
+		return 0, errors.Wrap(err, "count pending entries")
+	}
+
+	return count, nil
+}
+
+// eventKey returns key of event unique for event's type, post and post's revision.
+// Key of EventPostCreated of first revision is just post's url to match entries
+// enqueued before events were introduced.
+func eventKey(event entity.Event) string {
+	if event.Type == entity.EventPostCreated && event.Post.Revision <= 1 {
+		return event.Post.URL
+	}
+	return fmt.Sprintf("%s %s %d", event.Type, event.Post.URL, event.Post.Revision)
+}
@@ -2,3 +2,18 @@ package posts
 
 // publishedPostsCollection is name of collection in mongodb with published posts.
 const publishedPostsCollection = "publishedPosts"
+
+// postContentsCollection is name of collection in mongodb with contents of published
+// posts (used by Posts), content is stored separately from publishedPosts document.
+const postContentsCollection = "postContents"
+
+// namespaceExistsCode is mongodb's error code of creating collection which already exists.
+const namespaceExistsCode = 48
+
+// migratedField is field of publishedPosts document set to true when
+// it's posts are copied to posts collection by Standalone.
+const migratedField = "migratedToStandalone"
+
+// postsCollection is name of collection in mongodb with published posts
+// stored one document per post (used by Standalone).
+const postsCollection = "posts"
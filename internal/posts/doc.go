@@ -1,3 +1,4 @@
-// Package posts provides implementation for scanner.Posts interface -
-// it stores posts that have been published with scanner.Publisher interface (message brocker)
+// Package posts provides implementations for scanner.Posts interface -
+// they store posts that have been published with scanner.Publisher interface (message brocker).
+// Posts requires mongodb replica set for transactions, Standalone works with standalone instance.
 package posts
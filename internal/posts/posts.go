@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"gbu-scanner/internal/entity"
+	"gbu-scanner/internal/metrics"
 	"gbu-scanner/internal/scanner"
 
 	"gbu-scanner/pkg/logger"
@@ -34,8 +35,28 @@ func New(mongo *mongo.Client, database string, log logger.Logger) *Posts {
 	}
 }
 
-// Init creates document with empty posts array if it doesn't exist.
+// postContent is content of post stored in postContentsCollection by post's url. Contents
+// aren't stored in posts array as whole array is one document limited by 16MB.
+type postContent struct {
+	URL     string              `bson:"_id"`
+	Content *entity.PostContent `bson:"content"`
+}
+
+// Init creates document with empty posts array and collection of posts' contents if they don't exist.
+// Collection is created explicitly, as mongodb before 4.4 can't create collections in transactions.
 func (p *Posts) Init(ctx context.Context) error {
+	names, err := p.mongoDB.ListCollectionNames(ctx, bson.D{{Key: "name", Value: postContentsCollection}})
+	if err != nil {
+		return errors.Wrap(err, "list collections")
+	}
+
+	if len(names) == 0 {
+		err = p.mongoDB.CreateCollection(ctx, postContentsCollection)
+		if err != nil && !isNamespaceExistsError(err) {
+			return errors.Wrap(err, "create contents collection")
+		}
+	}
+
 	res := p.mongoDB.Collection(publishedPostsCollection).FindOne(ctx, bson.D{})
 	if res.Err() != nil && !errors.Is(res.Err(), mongo.ErrNoDocuments) {
 		return errors.Wrap(res.Err(), "find document")
@@ -59,6 +80,7 @@ func (p *Posts) Init(ctx context.Context) error {
 func (p *Posts) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
 	sess, err := p.mongo.StartSession(options.Session().SetCausalConsistency(true))
 	if err != nil {
+		metrics.StorageFailures.WithLabelValues("transaction").Inc()
 		return errors.Wrap(err, "start session")
 	}
 	defer sess.EndSession(ctx)
@@ -78,23 +100,58 @@ func (p *Posts) Transaction(ctx context.Context, fn func(ctx context.Context) er
 }
 
 func (p *Posts) Add(ctx context.Context, post entity.Post) error {
+	post, err := p.saveContent(ctx, post)
+	if err != nil {
+		metrics.StorageFailures.WithLabelValues("add").Inc()
+		return err
+	}
+
 	// As only one document with posts array in collection - empty filter used
-	_, err := p.mongoDB.Collection(publishedPostsCollection).UpdateOne(ctx, bson.D{}, bson.D{
+	_, err = p.mongoDB.Collection(publishedPostsCollection).UpdateOne(ctx, bson.D{}, bson.D{
 		{Key: "$push", Value: bson.D{
 			{Key: "posts", Value: post},
 		}},
 	})
 	if err != nil {
+		metrics.StorageFailures.WithLabelValues("add").Inc()
 		return errors.Wrap(err, "insert post")
 	}
 
 	return nil
 }
 
+func (p *Posts) Update(ctx context.Context, post entity.Post) error {
+	post, err := p.saveContent(ctx, post)
+	if err != nil {
+		metrics.StorageFailures.WithLabelValues("update").Inc()
+		return err
+	}
+
+	// Positional operator replaces the only element of posts array matched by filter.
+	_, err = p.mongoDB.Collection(publishedPostsCollection).UpdateOne(ctx, bson.D{
+		{Key: "posts.url", Value: post.URL},
+	}, bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "posts.$", Value: post},
+		}},
+	})
+	if err != nil {
+		metrics.StorageFailures.WithLabelValues("update").Inc()
+		return errors.Wrap(err, "update post")
+	}
+
+	return nil
+}
+
 func (p *Posts) GetAll(ctx context.Context) ([]entity.Post, error) {
 	// As only one document with posts array in collection - empty filter used
 	res := p.mongoDB.Collection(publishedPostsCollection).FindOne(ctx, bson.D{})
+	// Document doesn't exist if database wasn't initialized yet (read-only commands don't initialize it).
+	if errors.Is(res.Err(), mongo.ErrNoDocuments) {
+		return nil, nil
+	}
 	if res.Err() != nil {
+		metrics.StorageFailures.WithLabelValues("get_all").Inc()
 		return nil, errors.Wrap(res.Err(), "find document")
 	}
 
@@ -104,8 +161,71 @@ func (p *Posts) GetAll(ctx context.Context) ([]entity.Post, error) {
 
 	err := res.Decode(&doc)
 	if err != nil {
+		metrics.StorageFailures.WithLabelValues("get_all").Inc()
 		return nil, errors.Wrap(err, "decode document")
 	}
 
+	err = loadContents(ctx, p.mongoDB, doc.Posts)
+	if err != nil {
+		metrics.StorageFailures.WithLabelValues("get_all").Inc()
+		return nil, err
+	}
+
 	return doc.Posts, nil
 }
+
+// saveContent stores post's content (if any) to contents collection and returns post
+// without content to store it in posts array.
+func (p *Posts) saveContent(ctx context.Context, post entity.Post) (entity.Post, error) {
+	if post.Content == nil {
+		return post, nil
+	}
+
+	_, err := p.mongoDB.Collection(postContentsCollection).ReplaceOne(ctx,
+		bson.D{{Key: "_id", Value: post.URL}},
+		postContent{URL: post.URL, Content: post.Content},
+		options.Replace().SetUpsert(true),
+	)
+	if err != nil {
+		return entity.Post{}, errors.Wrap(err, "save post's content")
+	}
+
+	post.Content = nil
+
+	return post, nil
+}
+
+// loadContents sets contents of posts from contents collection. Posts stored with
+// content inside posts array (before contents collection was introduced) keep it.
+func loadContents(ctx context.Context, db *mongo.Database, posts []entity.Post) error {
+	cur, err := db.Collection(postContentsCollection).Find(ctx, bson.D{})
+	if err != nil {
+		return errors.Wrap(err, "find posts' contents")
+	}
+
+	var contents []postContent
+	err = cur.All(ctx, &contents)
+	if err != nil {
+		return errors.Wrap(err, "decode posts' contents")
+	}
+
+	byURL := make(map[string]*entity.PostContent, len(contents))
+	for _, c := range contents {
+		byURL[c.URL] = c.Content
+	}
+
+	for i := range posts {
+		if content, ok := byURL[posts[i].URL]; ok {
+			posts[i].Content = content
+		}
+	}
+
+	return nil
+}
+
+// isNamespaceExistsError returns true if err is mongodb's error
+// of creating collection which already exists.
+func isNamespaceExistsError(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && cmdErr.Code == namespaceExistsCode
+}
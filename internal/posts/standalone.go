@@ -0,0 +1,154 @@
+package posts
+
+import (
+	"context"
+
+	"gbu-scanner/internal/entity"
+	"gbu-scanner/internal/metrics"
+	"gbu-scanner/internal/scanner"
+
+	"gbu-scanner/pkg/logger"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Standalone is implementation for scanner.Posts interface that works with
+// standalone mongodb instance (without replica set). Instead of one document with
+// posts array and multi-document transactions it stores one document per post
+// with unique index on url, so adding same post twice is a no-op.
+type Standalone struct {
+	mongoDB *mongo.Database
+	log     logger.Logger
+}
+
+var _ scanner.Posts = &Standalone{}
+
+// NewStandalone returns scanner.Posts implementation for standalone mongodb.
+func NewStandalone(mongo *mongo.Client, database string, log logger.Logger) *Standalone {
+	return &Standalone{
+		mongoDB: mongo.Database(database),
+		log:     log,
+	}
+}
+
+// Init creates unique index on post's url if it doesn't exist and migrates
+// posts stored in transactions mode (see migrate).
+func (p *Standalone) Init(ctx context.Context) error {
+	_, err := p.mongoDB.Collection(postsCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "url", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return errors.Wrap(err, "create unique index on url")
+	}
+
+	err = p.migrate(ctx)
+	if err != nil {
+		return errors.Wrap(err, "migrate posts from transactions mode")
+	}
+
+	return nil
+}
+
+// migrate copies posts from publishedPosts document (transactions mode) to posts
+// collection once, so switching existing deployment to standalone mode doesn't look
+// like first run and doesn't publish all posts again. Document is marked as migrated
+// after copying, if copying is interrupted, it's repeated on next start (posts are upserted).
+func (p *Standalone) migrate(ctx context.Context) error {
+	res := p.mongoDB.Collection(publishedPostsCollection).FindOne(ctx, bson.D{
+		{Key: migratedField, Value: bson.D{{Key: "$ne", Value: true}}},
+	})
+	if errors.Is(res.Err(), mongo.ErrNoDocuments) {
+		return nil
+	}
+	if res.Err() != nil {
+		return errors.Wrap(res.Err(), "find document")
+	}
+
+	var doc struct {
+		ID    interface{}   `bson:"_id"`
+		Posts []entity.Post `bson:"posts"`
+	}
+
+	err := res.Decode(&doc)
+	if err != nil {
+		return errors.Wrap(err, "decode document")
+	}
+
+	err = loadContents(ctx, p.mongoDB, doc.Posts)
+	if err != nil {
+		return err
+	}
+
+	for _, post := range doc.Posts {
+		err = p.Add(ctx, post)
+		if err != nil {
+			return errors.Wrapf(err, "add post %q", post.URL)
+		}
+	}
+
+	_, err = p.mongoDB.Collection(publishedPostsCollection).UpdateOne(ctx,
+		bson.D{{Key: "_id", Value: doc.ID}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: migratedField, Value: true}}}},
+	)
+	if err != nil {
+		return errors.Wrap(err, "mark document as migrated")
+	}
+
+	p.log.Infof("%d posts are migrated from %s collection", len(doc.Posts), publishedPostsCollection)
+
+	return nil
+}
+
+// Transaction just calls fn as standalone mongodb doesn't support transactions.
+// Consistency is kept by unique index and upserts in Add: concurrent
+// iterations can't store one post twice.
+func (p *Standalone) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func (p *Standalone) Add(ctx context.Context, post entity.Post) error {
+	_, err := p.mongoDB.Collection(postsCollection).UpdateOne(ctx,
+		bson.D{{Key: "url", Value: post.URL}},
+		bson.D{{Key: "$setOnInsert", Value: post}},
+		options.Update().SetUpsert(true),
+	)
+	// Two concurrent upserts can both miss the document and one of
+	// them fails on unique index - post is stored anyway.
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		metrics.StorageFailures.WithLabelValues("add").Inc()
+		return errors.Wrap(err, "upsert post")
+	}
+
+	return nil
+}
+
+func (p *Standalone) Update(ctx context.Context, post entity.Post) error {
+	_, err := p.mongoDB.Collection(postsCollection).ReplaceOne(ctx, bson.D{{Key: "url", Value: post.URL}}, post)
+	if err != nil {
+		metrics.StorageFailures.WithLabelValues("update").Inc()
+		return errors.Wrap(err, "replace post")
+	}
+
+	return nil
+}
+
+func (p *Standalone) GetAll(ctx context.Context) ([]entity.Post, error) {
+	cur, err := p.mongoDB.Collection(postsCollection).Find(ctx, bson.D{})
+	if err != nil {
+		metrics.StorageFailures.WithLabelValues("get_all").Inc()
+		return nil, errors.Wrap(err, "find posts")
+	}
+
+	var posts []entity.Post
+	err = cur.All(ctx, &posts)
+	if err != nil {
+		metrics.StorageFailures.WithLabelValues("get_all").Inc()
+		return nil, errors.Wrap(err, "decode posts")
+	}
+
+	return posts, nil
+}
@@ -1,6 +1,10 @@
 package publisher
 
-import "time"
+import (
+	"time"
+
+	"gbu-scanner/internal/topology"
+)
 
 // RabbitConfig is configuration for rabbitmq's connection.
 type RabbitConfig struct {
@@ -16,5 +20,35 @@ type RabbitConfig struct {
 	Amqps bool
 	// ReconnectDelay is duration how long should wait before
 	// attempting to reconnect to rabbit after loosing connection.
+	// It's doubled after each failed attempt up to ReconnectMaxDelay.
 	ReconnectDelay time.Duration
+	// ReconnectMaxDelay is max delay between attempts to reconnect.
+	ReconnectMaxDelay time.Duration
+	// FailFast flag makes Publish fail with ErrNotConnected while reconnecting
+	// instead of waiting for reconnection.
+	FailFast bool
+	// ConfirmTimeout is duration how long Publish waits for
+	// broker's confirmation of published message.
+	ConfirmTimeout time.Duration
+	// Exchange is name of exchange where messages are published.
+	Exchange string
+	// ExchangeType is type of exchange: "fanout", "topic", "direct" or "headers".
+	ExchangeType string
+	// RoutingKey is template of messages' routing key, e.g. "{type}.{year}.{author}".
+	// Available placeholders are {type}, {source}, {year}, {month}, {author} and {topic}.
+	RoutingKey string
+	// DropUnroutable flag makes Publish treat message returned as unroutable (no queue is
+	// bound with it's routing key) as published instead of failing, it's only logged and counted.
+	DropUnroutable bool
+	// Topology is exchanges, queues and bindings declared on each (re)connect.
+	// If it has exchange named Exchange, it's declared with topology's parameters.
+	Topology topology.Topology
+	// SpoolPath is path of file where messages are spooled while rabbit is unavailable.
+	// Spooling is disabled if it's empty.
+	SpoolPath string
+	// SpoolMaxSize is max size (in bytes) of spool file.
+	SpoolMaxSize int64
+	// MessageFormat is format of published messages: FormatEnvelope, FormatLegacy,
+	// FormatCloudEventsStructured or FormatCloudEventsBinary.
+	MessageFormat string
 }
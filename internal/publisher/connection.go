@@ -0,0 +1,258 @@
+package publisher
+
+import (
+	"context"
+	"sync"
+
+	"gbu-scanner/internal/metrics"
+
+	"gbu-scanner/pkg/logger"
+	"gbu-scanner/pkg/sleep"
+	"gbu-scanner/pkg/wrappers/rabbit"
+
+	"github.com/pkg/errors"
+	"github.com/streadway/amqp"
+)
+
+// ConnectionState is state of connection to rabbit.
+type ConnectionState string
+
+const (
+	// StateConnecting is state before first connection is established.
+	StateConnecting ConnectionState = "connecting"
+	// StateConnected is state with open connection and channel.
+	StateConnected ConnectionState = "connected"
+	// StateReconnecting is state after connection or channel was closed and until it's restored.
+	StateReconnecting ConnectionState = "reconnecting"
+	// StateClosed is state after connection was closed on shutdown.
+	StateClosed ConnectionState = "closed"
+)
+
+// ErrNotConnected is returned by Publish in fail fast mode while publisher is reconnecting.
+var ErrNotConnected = errors.New("not connected to rabbit")
+
+// errChannelClosed is returned by Publish if channel is closed before broker's confirmation.
+var errChannelClosed = errors.New("channel closed before confirmation")
+
+// session is rabbit's connection and channel in confirm mode with
+// channels of confirmations and returns. It's replaced on each reconnect.
+type session struct {
+	conn *amqp.Connection
+	ch   *amqp.Channel
+	// confirms and returns get broker's acks/nacks and returned (unroutable) messages.
+	confirms chan amqp.Confirmation
+	returns  chan amqp.Return
+	// connClosed and chClosed get error when connection or channel is closed.
+	// They are separate as amqp closes each registered chan.
+	connClosed, chClosed chan *amqp.Error
+	// deliveryTag is delivery tag of last published message on channel.
+	// It's used only under Publisher's publishMu.
+	deliveryTag uint64
+}
+
+// close closes channel and connection, errors are ignored as they are closed already in most cases.
+func (s *session) close() {
+	_ = s.ch.Close()
+	_ = s.conn.Close()
+}
+
+// connection is manager of connection to rabbit. It watches closing of both connection and
+// channel and reconnects with capped exponential backoff. Current session is available with
+// method session, which blocks (or fails fast) while reconnecting.
+type connection struct {
+	cfg RabbitConfig
+	// setup prepares new channel (declares topology, enables confirm mode).
+	setup func(ch *amqp.Channel) error
+	log   logger.Logger
+
+	current *session
+	state   ConnectionState
+	// ready is closed when session is available, it's replaced when session is lost.
+	ready chan struct{}
+	mu    *sync.RWMutex // Protects current, state and ready.
+}
+
+// newConnection returns connection manager. Connection is established with method connect.
+func newConnection(cfg RabbitConfig, setup func(ch *amqp.Channel) error, log logger.Logger) *connection {
+	return &connection{
+		cfg:   cfg,
+		setup: setup,
+		log:   log,
+
+		state: StateConnecting,
+		ready: make(chan struct{}),
+		mu:    &sync.RWMutex{},
+	}
+}
+
+// connect establishes first connection.
+func (c *connection) connect() error {
+	s, err := c.dial()
+	if err != nil {
+		return err
+	}
+
+	c.setSession(s)
+
+	return nil
+}
+
+// run is a blocking method until context cancelled, it reconnects when connection or channel is
+// closed. When context is closed, connection is closed and publishing waiting for session fails.
+func (c *connection) run(ctx context.Context) {
+	for {
+		c.mu.RLock()
+		s := c.current
+		c.mu.RUnlock()
+
+		select {
+		case <-ctx.Done():
+			c.shutdown()
+			return
+		case closeErr := <-s.connClosed:
+			c.log.Errorf("rabbit connection closed: %v", closeErr) // closeErr is nil if closed gracefully.
+		case closeErr := <-s.chClosed:
+			c.log.Errorf("rabbit channel closed: %v", closeErr)
+		}
+
+		c.mu.Lock()
+		c.state = StateReconnecting
+		c.ready = make(chan struct{})
+		c.mu.Unlock()
+		metrics.Connected.Set(0)
+
+		s.close()
+
+		isCtxClosed := c.reconnect(ctx)
+		if isCtxClosed {
+			c.shutdown()
+			return
+		}
+	}
+}
+
+// reconnect dials rabbit until success with delays from RabbitConfig.ReconnectDelay doubling up to
+// RabbitConfig.ReconnectMaxDelay. Returns true if context was closed before reconnection.
+func (c *connection) reconnect(ctx context.Context) bool {
+	delay := c.cfg.ReconnectDelay
+	for attempt := 1; ; attempt++ {
+		isCtxClosed := sleep.WithContext(ctx, delay)
+		if isCtxClosed {
+			c.log.Info("could not reconnect to rabbit until context closed")
+			return true
+		}
+
+		s, err := c.dial()
+		if err != nil {
+			metrics.ReconnectAttempts.WithLabelValues(metrics.ResultError).Inc()
+			c.log.Warn(errors.Wrapf(err, "can't reconnect to rabbit (attempt #%d)", attempt))
+
+			delay *= 2
+			if delay > c.cfg.ReconnectMaxDelay {
+				delay = c.cfg.ReconnectMaxDelay
+			}
+			continue
+		}
+
+		metrics.ReconnectAttempts.WithLabelValues(metrics.ResultSuccess).Inc()
+		c.setSession(s)
+		c.log.Info("reconnected to rabbit")
+
+		return false
+	}
+}
+
+// dial connects to rabbit, opens channel and sets it up.
+func (c *connection) dial() (*session, error) {
+	cfg := c.cfg
+
+	conn, err := rabbit.Dial(cfg.Host, cfg.User, cfg.Pass, cfg.Vhost, cfg.Amqps)
+	if err != nil {
+		return nil, errors.Wrap(err, "connect to rabbit")
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		_ = conn.Close()
+		return nil, errors.Wrap(err, "get rabbit channel")
+	}
+
+	s := &session{conn: conn, ch: ch}
+
+	err = c.setup(ch)
+	if err != nil {
+		s.close()
+		return nil, errors.Wrap(err, "set up channel")
+	}
+
+	// Buffered to not block amqp's goroutine: return is always delivered before confirmation,
+	// so when Publish gets confirmation, return of the same message (if any) is already in buffer.
+	s.confirms = ch.NotifyPublish(make(chan amqp.Confirmation, 1))
+	s.returns = ch.NotifyReturn(make(chan amqp.Return, 1))
+
+	// Buffered as only first closing is read.
+	s.connClosed = conn.NotifyClose(make(chan *amqp.Error, 1))
+	s.chClosed = ch.NotifyClose(make(chan *amqp.Error, 1))
+
+	return s, nil
+}
+
+// setSession sets current session and marks connection as connected.
+func (c *connection) setSession(s *session) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.current = s
+	c.state = StateConnected
+	close(c.ready)
+	metrics.Connected.Set(1)
+}
+
+// shutdown closes current session on context close.
+func (c *connection) shutdown() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.state == StateConnected {
+		c.current.close()
+	} else {
+		close(c.ready) // Wakes up publishing waiting for reconnection.
+	}
+
+	c.state = StateClosed
+	metrics.Connected.Set(0)
+	c.log.Info("rabbit connection closed")
+}
+
+// session returns current session. While reconnecting it waits for reconnection
+// until context is closed or returns ErrNotConnected if failFast is set.
+func (c *connection) session(ctx context.Context, failFast bool) (*session, error) {
+	for {
+		c.mu.RLock()
+		s, state, ready := c.current, c.state, c.ready
+		c.mu.RUnlock()
+
+		switch {
+		case state == StateConnected:
+			return s, nil
+		case state == StateClosed:
+			return nil, errors.New("rabbit connection closed")
+		case failFast:
+			return nil, ErrNotConnected
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrap(ctx.Err(), "wait for reconnection to rabbit")
+		case <-ready:
+		}
+	}
+}
+
+// getState returns connection's state.
+func (c *connection) getState() ConnectionState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.state
+}
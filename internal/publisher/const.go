@@ -1,3 +1,53 @@
 package publisher
 
-const postsExchange = "posts"
+// Default exchange's name and type (RabbitConfig.Exchange and RabbitConfig.ExchangeType).
+const (
+	DefaultExchange     = "posts"
+	DefaultExchangeType = "fanout"
+)
+
+// Routing key's constants.
+const (
+	// routingKeyUnknown is value of routing key's placeholder if event has no such field.
+	routingKeyUnknown = "unknown"
+	// maxRoutingKeyLength is max length (in bytes) of routing key allowed by AMQP.
+	maxRoutingKeyLength = 255
+)
+
+// messageIDLength is length (in bytes) of random message's identifier.
+const messageIDLength = 16
+
+// Available values for RabbitConfig.MessageFormat.
+const (
+	// FormatEnvelope is versioned envelope with event's metadata and payload,
+	// described with JSON Schema in api/post-event.v1.schema.json.
+	FormatEnvelope = "envelope"
+	// FormatLegacy is format used before envelope was introduced: bare post's JSON
+	// for "post.created" events and {"post": ..., "diff": ...} for others.
+	FormatLegacy = "legacy"
+	// FormatCloudEventsStructured is CloudEvents 1.0 in structured content mode:
+	// whole event is message's body with content type "application/cloudevents+json".
+	FormatCloudEventsStructured = "cloudevents-structured"
+	// FormatCloudEventsBinary is CloudEvents 1.0 in binary content mode: event's
+	// attributes are message's headers with "cloudEvents:" prefix and body is event's data.
+	FormatCloudEventsBinary = "cloudevents-binary"
+)
+
+// CloudEvents' constants.
+const (
+	cloudEventsSpecVersion = "1.0"
+	// cloudEventsSourcePrefix is prefix of events' source attribute, it's followed by blog's name.
+	cloudEventsSourcePrefix = "gbu-scanner/"
+	// cloudEventsHeaderPrefix is prefix of AMQP headers with attributes in binary content mode.
+	cloudEventsHeaderPrefix = "cloudEvents:"
+	contentTypeCloudEvents  = "application/cloudevents+json"
+)
+
+// envelopeSchemaVersion is version of envelope's schema, it's incremented on incompatible changes.
+const envelopeSchemaVersion = 1
+
+// contentTypeJSON is content type of messages' bodies.
+const contentTypeJSON = "application/json"
+
+// spoolFileMode is permissions of created spool file.
+const spoolFileMode = 0o600
@@ -0,0 +1,142 @@
+package publisher
+
+import (
+	"encoding/json"
+	"time"
+
+	"gbu-scanner/internal/entity"
+
+	"github.com/pkg/errors"
+	"github.com/streadway/amqp"
+)
+
+// payload is event's data: post and it's diff for "post.updated" events.
+type payload struct {
+	Post entity.Post          `json:"post"`
+	Diff []entity.FieldChange `json:"diff,omitempty"`
+}
+
+// envelope is message's body in FormatEnvelope.
+type envelope struct {
+	ID            string           `json:"id"`
+	Type          entity.EventType `json:"type"`
+	SchemaVersion int              `json:"schemaVersion"`
+	Source        string           `json:"source"`
+	OccurredAt    time.Time        `json:"occurredAt"`
+	Replay        bool             `json:"replay,omitempty"`
+	Payload       payload          `json:"payload"`
+}
+
+// cloudEvent is CloudEvents 1.0 event in structured content mode (JSON format).
+type cloudEvent struct {
+	SpecVersion     string    `json:"specversion"`
+	ID              string    `json:"id"`
+	Source          string    `json:"source"`
+	Type            string    `json:"type"`
+	Subject         string    `json:"subject"`
+	Time            time.Time `json:"time"`
+	DataContentType string    `json:"datacontenttype"`
+	// Replay is extension attribute, it's set only for replayed events.
+	Replay bool    `json:"replay,omitempty"`
+	Data   payload `json:"data"`
+}
+
+// isKnownFormat returns true if format is one of available messages' formats.
+func isKnownFormat(format string) bool {
+	switch format {
+	case FormatEnvelope, FormatLegacy, FormatCloudEventsStructured, FormatCloudEventsBinary:
+		return true
+	default:
+		return false
+	}
+}
+
+// makePublishing makes message with event in specified format. Message's id is event's id,
+// or random one if event has no id.
+func makePublishing(format string, event entity.Event) (amqp.Publishing, error) {
+	id := event.ID
+	if id == "" {
+		var err error
+		id, err = newMessageID()
+		if err != nil {
+			return amqp.Publishing{}, errors.Wrap(err, "generate message id")
+		}
+	}
+
+	timestamp := event.OccurredAt
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	publishing := amqp.Publishing{
+		MessageId:    id,
+		Type:         string(event.Type),
+		ContentType:  contentTypeJSON,
+		DeliveryMode: amqp.Persistent,
+		Timestamp:    timestamp,
+		Headers: amqp.Table{
+			// Allows consumers to route posts by source without decoding body.
+			"source": event.Post.Source,
+		},
+	}
+
+	if event.Replay {
+		// Allows consumers to distinguish backfill from live events.
+		publishing.Headers["replay"] = true
+	}
+
+	var body interface{}
+	switch format {
+	case FormatEnvelope:
+		publishing.Headers["schema_version"] = envelopeSchemaVersion
+		body = envelope{
+			ID:            id,
+			Type:          event.Type,
+			SchemaVersion: envelopeSchemaVersion,
+			Source:        event.Post.Source,
+			OccurredAt:    timestamp,
+			Replay:        event.Replay,
+			Payload:       payload{Post: event.Post, Diff: event.Diff},
+		}
+	case FormatLegacy:
+		body = event.Post
+		if event.Type != entity.EventPostCreated {
+			body = payload{Post: event.Post, Diff: event.Diff}
+		}
+	case FormatCloudEventsStructured:
+		publishing.ContentType = contentTypeCloudEvents
+		body = cloudEvent{
+			SpecVersion:     cloudEventsSpecVersion,
+			ID:              id,
+			Source:          cloudEventsSourcePrefix + event.Post.Source,
+			Type:            string(event.Type),
+			Subject:         event.Post.URL,
+			Time:            timestamp,
+			DataContentType: contentTypeJSON,
+			Replay:          event.Replay,
+			Data:            payload{Post: event.Post, Diff: event.Diff},
+		}
+	case FormatCloudEventsBinary:
+		// Content type property is event's datacontenttype in binary mode.
+		publishing.Headers[cloudEventsHeaderPrefix+"specversion"] = cloudEventsSpecVersion
+		publishing.Headers[cloudEventsHeaderPrefix+"id"] = id
+		publishing.Headers[cloudEventsHeaderPrefix+"source"] = cloudEventsSourcePrefix + event.Post.Source
+		publishing.Headers[cloudEventsHeaderPrefix+"type"] = string(event.Type)
+		publishing.Headers[cloudEventsHeaderPrefix+"subject"] = event.Post.URL
+		publishing.Headers[cloudEventsHeaderPrefix+"time"] = timestamp.UTC().Format(time.RFC3339Nano)
+		if event.Replay {
+			publishing.Headers[cloudEventsHeaderPrefix+"replay"] = true
+		}
+		body = payload{Post: event.Post, Diff: event.Diff}
+	default:
+		return amqp.Publishing{}, errors.Errorf("unknown message format %q", format)
+	}
+
+	var err error
+	publishing.Body, err = json.Marshal(body)
+	if err != nil {
+		return amqp.Publishing{}, errors.Wrap(err, "marshal body")
+	}
+
+	return publishing, nil
+}
@@ -0,0 +1,18 @@
+package publisher
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/pkg/errors"
+)
+
+// newMessageID returns random identifier for published message.
+func newMessageID() (string, error) {
+	b := make([]byte, messageIDLength)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", errors.Wrap(err, "read random bytes")
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -7,11 +7,11 @@ import (
 	"time"
 
 	"gbu-scanner/internal/entity"
+	"gbu-scanner/internal/metrics"
 	"gbu-scanner/internal/scanner"
 
 	"gbu-scanner/pkg/logger"
 	"gbu-scanner/pkg/sleep"
-	"gbu-scanner/pkg/wrappers/rabbit"
 
 	"github.com/pkg/errors"
 	"github.com/streadway/amqp"
@@ -20,12 +20,20 @@ import (
 // Publisher is implementation for scanner.Publisher interface.
 type Publisher struct {
 	rabbitConfig RabbitConfig
-	rabbit       *amqp.Channel
+	routingKey   routingKey  // Parsed in Init method.
+	conn         *connection // Initialized in Init method.
+	spool        *spool      // Opened in Init method if RabbitConfig.SpoolPath is set.
 	log          logger.Logger
 
-	// RWMutex Locks used to connect to rabbit (Init method).
-	// RWMutex RLocks used to use connection.
-	mu *sync.RWMutex
+	// publishMu serializes publishing, so each Publish call waits confirmation of it's own message.
+	// It protects spool and spool's backoff too.
+	publishMu *sync.Mutex
+
+	// spoolBackoff is delay before next draining of spool after broker rejected spooled
+	// event, it's doubled on each rejection up to RabbitConfig.ReconnectMaxDelay.
+	spoolBackoff time.Duration
+	// spoolRetryAt is time before which spool isn't drained after rejection.
+	spoolRetryAt time.Time
 }
 
 var _ scanner.Publisher = &Publisher{}
@@ -34,96 +42,373 @@ var _ scanner.Publisher = &Publisher{}
 func New(rabbitConfig RabbitConfig, log logger.Logger) *Publisher {
 	return &Publisher{
 		rabbitConfig: rabbitConfig,
-		rabbit:       nil, // Initialized in Init method.
 		log:          log,
 
-		mu: &sync.RWMutex{},
+		publishMu: &sync.Mutex{},
 	}
 }
 
 // Init connects to rabbit and gets rabbit channel, after what
 // initializes rabbit's entiies like exchanges, queues etc.
-// It also registers a handler for channel closed event to reconnect.
-// Close handler uses processCtx for it's calls because ctx for Init's call
-// can be another: for example, limited as WithTimeout.
+// It also starts connection manager which reconnects when connection or channel
+// is closed, until processCtx is closed (then connection is closed too).
+// With spool messages spooled by previous runs are published after connecting.
 func (p *Publisher) Init(ctx, processCtx context.Context) error {
-	p.mu.Lock()
-	defer p.mu.Unlock()
+	if !isKnownFormat(p.rabbitConfig.MessageFormat) {
+		return errors.Errorf("unknown message format %q", p.rabbitConfig.MessageFormat)
+	}
+
+	if !isKnownExchangeType(p.rabbitConfig.ExchangeType) {
+		return errors.Errorf("unknown exchange type %q", p.rabbitConfig.ExchangeType)
+	}
+
+	routingKey, err := parseRoutingKey(p.rabbitConfig.RoutingKey)
+	if err != nil {
+		return errors.Wrap(err, "parse routing key")
+	}
+	p.routingKey = routingKey
+
+	if p.rabbitConfig.SpoolPath != "" {
+		spool, err := openSpool(p.rabbitConfig.SpoolPath, p.rabbitConfig.SpoolMaxSize)
+		if err != nil {
+			return errors.Wrap(err, "open spool")
+		}
+
+		p.spool = spool
+		metrics.SpooledMessages.Set(float64(spool.len()))
+
+		if spool.len() != 0 {
+			p.log.Warnf("%d messages are left in spool from previous run", spool.len())
+		}
+	}
+
+	p.conn = newConnection(p.rabbitConfig, p.setupChannel, p.log)
+
+	err = p.conn.connect()
+	if err != nil {
+		return errors.Wrap(err, "connect")
+	}
+
+	go p.conn.run(processCtx)
+
+	if p.spool != nil {
+		go p.runSpoolDrainer(processCtx)
+	}
+
+	return nil
+}
 
+// setupChannel declares topology and exchange and puts channel into confirm mode.
+// It's called on each (re)connect.
+func (p *Publisher) setupChannel(ch *amqp.Channel) error {
 	cfg := p.rabbitConfig
 
-	conn, err := rabbit.Dial(cfg.Host, cfg.User, cfg.Pass, cfg.Vhost, cfg.Amqps)
+	err := cfg.Topology.Apply(ch)
+	if err != nil {
+		return errors.Wrap(err, "apply topology")
+	}
+
+	if !cfg.Topology.HasExchange(cfg.Exchange) {
+		err = ch.ExchangeDeclare(cfg.Exchange, cfg.ExchangeType, true, false, false, false, nil)
+		if err != nil {
+			return errors.Wrap(err, "declare exchange")
+		}
+	}
+
+	err = ch.Confirm(false)
+	if err != nil {
+		return errors.Wrap(err, "put channel into confirm mode")
+	}
+
+	return nil
+}
+
+// State returns state of connection to rabbit.
+func (p *Publisher) State() ConnectionState {
+	if p.conn == nil {
+		return StateConnecting
+	}
+
+	return p.conn.getState()
+}
+
+// Ready returns error if publisher has no open rabbit connection and channel
+// (not initialized yet, reconnecting or closed).
+func (p *Publisher) Ready(ctx context.Context) error {
+	state := p.State()
+	if state != StateConnected {
+		return errors.Errorf("rabbit connection is %s", state)
+	}
+
+	return nil
+}
+
+// Publish publishes event and waits until broker confirms it. Message is published
+// as mandatory, so if it can't be routed to any queue, it's returned by broker and
+// Publish returns error (unless RabbitConfig.DropUnroutable is set). Waiting is limited with RabbitConfig.ConfirmTimeout.
+// While publisher is reconnecting, Publish waits for reconnection until ctx is closed
+// or fails with ErrNotConnected if RabbitConfig.FailFast is set.
+//
+// With spool event is spooled (and Publish returns nil) instead if rabbit is unavailable.
+// Spooled events are published before new ones, so while they can't be drained
+// new events are spooled too to keep order.
+func (p *Publisher) Publish(ctx context.Context, event entity.Event) error {
+	p.publishMu.Lock()
+	defer p.publishMu.Unlock()
+
+	if p.spool == nil {
+		return p.publish(ctx, event, p.rabbitConfig.FailFast)
+	}
+
+	if p.spool.len() != 0 {
+		err := p.drainSpool(ctx)
+		if err != nil {
+			p.log.Warn(errors.Wrap(err, "can't drain spool"))
+			return p.spoolEvent(event)
+		}
+	}
+
+	err := p.publish(ctx, event, true)
+	if isUnavailable(err) {
+		p.log.Warn(errors.Wrap(err, "rabbit is unavailable"))
+		return p.spoolEvent(event)
+	}
+
+	return err
+}
+
+// publish publishes event and waits for confirmation, see Publish.
+func (p *Publisher) publish(ctx context.Context, event entity.Event, failFast bool) error {
+	publishing, err := makePublishing(p.rabbitConfig.MessageFormat, event)
 	if err != nil {
-		return errors.Wrap(err, "connect to rabbit")
+		metrics.PublishFailures.WithLabelValues("encode").Inc()
+		return errors.Wrap(err, "make message")
 	}
 
-	ch, err := conn.Channel()
+	routingKey, err := p.routingKey.build(event)
 	if err != nil {
-		return errors.Wrap(err, "get rabbit channel")
+		metrics.PublishFailures.WithLabelValues("encode").Inc()
+		return errors.Wrap(err, "make routing key")
 	}
 
-	err = ch.ExchangeDeclare(postsExchange, amqp.ExchangeFanout, true, false, false, false, nil)
+	s, err := p.conn.session(ctx, failFast)
 	if err != nil {
-		return errors.Wrap(err, "declare exchange")
+		metrics.PublishFailures.WithLabelValues("disconnected").Inc()
+		return errors.Wrap(err, "get rabbit session")
 	}
 
-	errs := make(chan *amqp.Error)
-	ch.NotifyClose(errs)
+	err = s.ch.Publish(p.rabbitConfig.Exchange, routingKey, true, false, publishing)
+	if err != nil {
+		metrics.PublishFailures.WithLabelValues("publish").Inc()
+		return errors.Wrap(err, "publish message to rabbit")
+	}
+	s.deliveryTag++
 
-	handleChannelClose := func() {
-		closeErr := <-errs // This chan will get a value when rabbit channel will be closed.
+	err = p.waitConfirmation(ctx, s, publishing.MessageId)
+	if err != nil {
+		return errors.Wrap(err, "wait confirmation")
+	}
 
-		p.log.Error(errors.Wrap(closeErr, "rabbit channel closed"))
+	metrics.Published.Inc()
 
-		if !conn.IsClosed() {
-			err := conn.Close()
-			if err != nil {
-				p.log.Error(errors.Wrap(err, "can't close rabbit connection"))
+	return nil
+}
+
+// waitConfirmation waits broker's ack/nack for message with specified delivery tag.
+// Confirmations and returns of previous messages (which Publish stopped waiting by timeout) are skipped.
+// Returns are read while waiting too, otherwise stale return in full buffer blocks amqp's goroutine.
+func (p *Publisher) waitConfirmation(ctx context.Context, s *session, messageID string) error {
+	ctx, cancel := context.WithTimeout(ctx, p.rabbitConfig.ConfirmTimeout)
+	defer cancel()
+
+	confirms, returns := s.confirms, s.returns
+	var returned *amqp.Return
+
+	for {
+		select {
+		case <-ctx.Done():
+			metrics.PublishFailures.WithLabelValues("timeout").Inc()
+			return errors.Wrap(ctx.Err(), "no confirmation from broker")
+		case ret, ok := <-returns:
+			if !ok {
+				returns = nil // Channel closed, confirms will be closed too.
+				continue
 			}
-		}
 
-		for attempt, isConnected := 1, false; !isConnected; attempt++ {
-			isCtxClosed := sleep.WithContext(processCtx, cfg.ReconnectDelay)
-			if isCtxClosed {
-				p.log.Info("could not re-init publisher until context closed")
-				return
+			if ret.MessageId == messageID {
+				returned = &ret
+			}
+		case confirmation, ok := <-confirms:
+			if !ok {
+				metrics.PublishFailures.WithLabelValues("closed").Inc()
+				return errChannelClosed
 			}
 
-			err := p.Init(processCtx, processCtx)
-			if err != nil {
-				p.log.Warn(errors.Wrapf(err, "can't re-init publisher (attempt #%d)", attempt))
+			if confirmation.DeliveryTag < s.deliveryTag {
 				continue
 			}
 
-			isConnected = true
-		}
+			if !confirmation.Ack {
+				metrics.PublishFailures.WithLabelValues("nack").Inc()
+				return errors.New("message nacked by broker")
+			}
+
+			// Return is delivered before confirmation, so it's either already read or in buffer.
+			if returned == nil {
+				returned = takeReturned(s, messageID)
+			}
+
+			if returned != nil && returned.ReplyCode == amqp.NoRoute && p.rabbitConfig.DropUnroutable {
+				p.log.Warnf("message is unroutable and dropped, no queue is bound to exchange %q with key %q",
+					returned.Exchange, returned.RoutingKey)
+				metrics.Unroutable.Inc()
+				return nil
+			}
 
-		p.log.Info("reconnected to rabbit")
+			if returned != nil {
+				metrics.PublishFailures.WithLabelValues("returned").Inc()
+				return errors.Errorf("message returned by broker: %d %s", returned.ReplyCode, returned.ReplyText)
+			}
+
+			return nil
+		}
 	}
-	go handleChannelClose()
+}
 
-	p.rabbit = ch
+// takeReturned reads buffered returns without blocking and returns one of message with messageID.
+func takeReturned(s *session, messageID string) *amqp.Return {
+	for {
+		select {
+		case ret, ok := <-s.returns:
+			if !ok {
+				return nil
+			}
 
-	return nil
+			if ret.MessageId == messageID {
+				return &ret
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// isUnavailable returns true if publishing error is caused by unavailability of rabbit:
+// there is no connection or it was closed while publishing.
+func isUnavailable(err error) bool {
+	return errors.Is(err, ErrNotConnected) || errors.Is(err, errChannelClosed) || errors.Is(err, amqp.ErrClosed)
 }
 
-func (p *Publisher) Publish(ctx context.Context, post entity.Post) error {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
+// backoffSpool postpones draining of spool starting with RabbitConfig.ReconnectDelay
+// and doubling delay up to RabbitConfig.ReconnectMaxDelay.
+func (p *Publisher) backoffSpool() {
+	p.spoolBackoff *= 2
+	if p.spoolBackoff == 0 {
+		p.spoolBackoff = p.rabbitConfig.ReconnectDelay
+	}
+	if p.spoolBackoff > p.rabbitConfig.ReconnectMaxDelay {
+		p.spoolBackoff = p.rabbitConfig.ReconnectMaxDelay
+	}
+
+	p.spoolRetryAt = time.Now().Add(p.spoolBackoff)
+}
 
-	encoded, err := json.Marshal(post)
+// spoolEvent appends event to spool. If spool is full, error is returned,
+// so event stays pending in outbox.
+func (p *Publisher) spoolEvent(event entity.Event) error {
+	record, err := json.Marshal(event)
 	if err != nil {
-		return errors.Wrap(err, "encode post to JSON")
+		metrics.PublishFailures.WithLabelValues("encode").Inc()
+		return errors.Wrap(err, "encode event")
 	}
 
-	err = p.rabbit.Publish(postsExchange, "", false, false, amqp.Publishing{
-		DeliveryMode: amqp.Persistent,
-		Timestamp:    time.Now(),
-		Body:         encoded,
-	})
+	err = p.spool.append(record)
 	if err != nil {
-		return errors.Wrap(err, "publish message to rabbit")
+		metrics.PublishFailures.WithLabelValues("spool").Inc()
+		return errors.Wrap(err, "spool event")
 	}
 
+	metrics.SpooledMessages.Set(float64(p.spool.len()))
+	p.log.Infof("%s event of post %q is spooled, %d events in spool", event.Type, event.Post.URL, p.spool.len())
+
 	return nil
 }
+
+// drainSpool publishes spooled events in order until spool is empty or publishing fails.
+// Published events are dropped from spool even if publishing of next one fails, so they
+// are published again only if process crashes before dropping. After broker rejected
+// spooled event, draining fails until backoff's delay passes (see backoffSpool).
+func (p *Publisher) drainSpool(ctx context.Context) error {
+	if time.Now().Before(p.spoolRetryAt) {
+		return errors.Errorf("spooled event was rejected by broker, next attempt at %s", p.spoolRetryAt.Format(time.RFC3339))
+	}
+
+	records, err := p.spool.records()
+	if err != nil {
+		return errors.Wrap(err, "read spool")
+	}
+
+	published := 0
+	for _, record := range records {
+		var event entity.Event
+		decodeErr := json.Unmarshal(record, &event)
+		if decodeErr != nil {
+			// Broken record can't be published ever, it would block spool forever.
+			p.log.Error(errors.Wrapf(decodeErr, "can't decode spooled event, it's dropped: %s", record))
+			metrics.SpoolDropped.Inc()
+			published++
+			continue
+		}
+
+		err = p.publish(ctx, event, true)
+		if err != nil {
+			err = errors.Wrapf(err, "publish spooled %s event of post %q", event.Type, event.Post.URL)
+			// Event rejected by broker (nacked or returned) is already marked as sent in outbox,
+			// so it's kept in spool and publishing is retried with backoff. Without backoff
+			// spool is drained again as soon as rabbit is available.
+			if !isUnavailable(err) {
+				p.backoffSpool()
+				err = errors.Wrapf(err, "next attempt in %s", p.spoolBackoff)
+			}
+			break
+		}
+		published++
+	}
+
+	if err == nil {
+		p.spoolBackoff = 0
+	}
+
+	dropErr := p.spool.drop(published)
+	metrics.SpooledMessages.Set(float64(p.spool.len()))
+	if dropErr != nil {
+		return errors.Wrap(dropErr, "drop published events from spool")
+	}
+
+	if published != 0 {
+		p.log.Infof("%d spooled events are published, %d left in spool", published, p.spool.len())
+	}
+
+	return err
+}
+
+// runSpoolDrainer is a blocking method until context closed, it drains spool when
+// connection to rabbit is restored, even if there are no new events to publish.
+// Spool is checked every RabbitConfig.ReconnectDelay.
+func (p *Publisher) runSpoolDrainer(ctx context.Context) {
+	for {
+		p.publishMu.Lock()
+		if p.spool.len() != 0 && p.State() == StateConnected && !time.Now().Before(p.spoolRetryAt) {
+			err := p.drainSpool(ctx)
+			if err != nil {
+				p.log.Warn(errors.Wrap(err, "can't drain spool"))
+			}
+		}
+		p.publishMu.Unlock()
+
+		isCtxClosed := sleep.WithContext(ctx, p.rabbitConfig.ReconnectDelay)
+		if isCtxClosed {
+			return
+		}
+	}
+}
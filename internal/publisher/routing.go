@@ -0,0 +1,137 @@
+package publisher
+
+import (
+	"strings"
+	"unicode"
+
+	"gbu-scanner/internal/entity"
+
+	"github.com/pkg/errors"
+	"github.com/streadway/amqp"
+)
+
+// routingKeyFields are placeholders available in routing key's template.
+// Values of fields which are not fixed words are slugified, so they can't contain dots.
+var routingKeyFields = map[string]func(event entity.Event) string{
+	"type": func(event entity.Event) string {
+		return string(event.Type)
+	},
+	"source": func(event entity.Event) string {
+		return slugOrUnknown(event.Post.Source)
+	},
+	"year": func(event entity.Event) string {
+		return event.Post.Date.Format("2006")
+	},
+	"month": func(event entity.Event) string {
+		return event.Post.Date.Format("01")
+	},
+	"author": func(event entity.Event) string {
+		return slugOrUnknown(event.Post.Author)
+	},
+	// topic is post's first tag, available only with enrichment enabled.
+	"topic": func(event entity.Event) string {
+		if event.Post.Content == nil || len(event.Post.Content.Tags) == 0 {
+			return routingKeyUnknown
+		}
+		return slugOrUnknown(event.Post.Content.Tags[0])
+	},
+}
+
+// routingKey is parsed template of message's routing key, e.g. "post.{year}.{author}".
+// Placeholders in curly braces are replaced with event's fields (see routingKeyFields).
+type routingKey struct {
+	// parts are literal strings and placeholders' names (with isField set) in template's order.
+	parts []routingKeyPart
+}
+
+// routingKeyPart is literal part of routing key's template or placeholder.
+type routingKeyPart struct {
+	value   string
+	isField bool
+}
+
+// parseRoutingKey parses routing key's template. Empty template makes empty routing key.
+func parseRoutingKey(template string) (routingKey, error) {
+	var key routingKey
+
+	for rest := template; rest != ""; {
+		start := strings.IndexByte(rest, '{')
+		if start == -1 {
+			key.parts = append(key.parts, routingKeyPart{value: rest})
+			break
+		}
+
+		if start != 0 {
+			key.parts = append(key.parts, routingKeyPart{value: rest[:start]})
+		}
+
+		end := strings.IndexByte(rest[start:], '}')
+		if end == -1 {
+			return routingKey{}, errors.Errorf("unclosed placeholder in %q", template)
+		}
+
+		field := rest[start+1 : start+end]
+		if _, ok := routingKeyFields[field]; !ok {
+			return routingKey{}, errors.Errorf("unknown placeholder {%s}", field)
+		}
+
+		key.parts = append(key.parts, routingKeyPart{value: field, isField: true})
+		rest = rest[start+end+1:]
+	}
+
+	return key, nil
+}
+
+// build returns routing key of event's message.
+func (k routingKey) build(event entity.Event) (string, error) {
+	var b strings.Builder
+	for _, part := range k.parts {
+		if part.isField {
+			b.WriteString(routingKeyFields[part.value](event))
+		} else {
+			b.WriteString(part.value)
+		}
+	}
+
+	if b.Len() > maxRoutingKeyLength {
+		return "", errors.Errorf("routing key %q is longer than %d bytes", b.String(), maxRoutingKeyLength)
+	}
+
+	return b.String(), nil
+}
+
+// slugOrUnknown returns s in lower case with all sequences of characters other than
+// letters and digits replaced with "-", e.g. "Russ Cox" becomes "russ-cox".
+// routingKeyUnknown is returned if there are no letters and digits in s.
+func slugOrUnknown(s string) string {
+	var b strings.Builder
+	needDash := false
+	for _, r := range strings.ToLower(s) {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			needDash = b.Len() != 0
+			continue
+		}
+
+		if needDash {
+			b.WriteByte('-')
+			needDash = false
+		}
+		b.WriteRune(r)
+	}
+
+	if b.Len() == 0 {
+		return routingKeyUnknown
+	}
+
+	return b.String()
+}
+
+// isKnownExchangeType returns true if kind is one of rabbit's exchanges' types.
+func isKnownExchangeType(kind string) bool {
+	switch kind {
+	case amqp.ExchangeDirect, amqp.ExchangeFanout, amqp.ExchangeTopic, amqp.ExchangeHeaders:
+		return true
+	default:
+		return false
+	}
+}
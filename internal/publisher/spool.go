@@ -0,0 +1,205 @@
+package publisher
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// errSpoolFull is returned when record doesn't fit into spool's size limit.
+var errSpoolFull = errors.New("spool is full")
+
+// spool is append-only file of records (one JSON per line), each append is fsynced.
+// Records are removed from the beginning of the file only, so they are read in appending order.
+// spool is not safe for concurrent use.
+type spool struct {
+	path    string
+	maxSize int64
+
+	file  *os.File // Opened in append mode.
+	size  int64
+	count int
+}
+
+// openSpool opens (or creates) spool file and counts records left from previous runs.
+// Incomplete last record (process crashed while appending) is truncated, otherwise
+// next appended record would be glued to it.
+func openSpool(path string, maxSize int64) (*spool, error) {
+	s := &spool{path: path, maxSize: maxSize}
+
+	err := s.open()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "read spool file")
+	}
+
+	if complete := int64(bytes.LastIndexByte(data, '\n') + 1); complete != s.size {
+		err = s.file.Truncate(complete)
+		if err != nil {
+			return nil, errors.Wrap(err, "truncate incomplete record")
+		}
+		s.size = complete
+	}
+
+	records, err := s.records()
+	if err != nil {
+		return nil, errors.Wrap(err, "read records")
+	}
+	s.count = len(records)
+
+	return s, nil
+}
+
+// open opens spool's file for appending and gets it's size.
+func (s *spool) open() error {
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_RDWR, spoolFileMode)
+	if err != nil {
+		return errors.Wrap(err, "open spool file")
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return errors.Wrap(err, "stat spool file")
+	}
+
+	s.file, s.size = file, info.Size()
+
+	return nil
+}
+
+// len returns count of records in spool.
+func (s *spool) len() int {
+	return s.count
+}
+
+// append appends record to the end of spool and syncs file to disk.
+// Returns errSpoolFull if spool's size would exceed maxSize.
+func (s *spool) append(record []byte) error {
+	line := append(record, '\n')
+	if s.size+int64(len(line)) > s.maxSize {
+		return errSpoolFull
+	}
+
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+	if err != nil {
+		return errors.Wrap(err, "write record")
+	}
+
+	err = s.file.Sync()
+	if err != nil {
+		return errors.Wrap(err, "sync spool file")
+	}
+
+	s.count++
+
+	return nil
+}
+
+// records returns all records in appending order.
+func (s *spool) records() ([][]byte, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, errors.Wrap(err, "read spool file")
+	}
+
+	var records [][]byte
+	for _, line := range bytes.Split(data, []byte{'\n'}) {
+		if len(line) != 0 {
+			records = append(records, line)
+		}
+	}
+
+	return records, nil
+}
+
+// drop removes first n records. Remaining records are written to temporary
+// file, which replaces spool's file, so crash while dropping doesn't lose records.
+func (s *spool) drop(n int) error {
+	if n == 0 {
+		return nil
+	}
+
+	records, err := s.records()
+	if err != nil {
+		return errors.Wrap(err, "read records")
+	}
+
+	if n >= len(records) {
+		err = s.file.Truncate(0)
+		if err != nil {
+			return errors.Wrap(err, "truncate spool file")
+		}
+
+		s.size, s.count = 0, 0
+
+		return errors.Wrap(s.file.Sync(), "sync spool file")
+	}
+
+	tmpPath := s.path + ".tmp"
+	err = writeRecords(tmpPath, records[n:])
+	if err != nil {
+		return errors.Wrap(err, "write remaining records")
+	}
+
+	err = os.Rename(tmpPath, s.path)
+	if err != nil {
+		return errors.Wrap(err, "replace spool file")
+	}
+
+	err = syncDir(filepath.Dir(s.path))
+	if err != nil {
+		return errors.Wrap(err, "sync spool's directory")
+	}
+
+	_ = s.file.Close()
+
+	err = s.open()
+	if err != nil {
+		return err
+	}
+	s.count = len(records) - n
+
+	return nil
+}
+
+// writeRecords writes records to new file and syncs it to disk.
+func writeRecords(path string, records [][]byte) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, spoolFileMode)
+	if err != nil {
+		return errors.Wrap(err, "open file")
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	for _, record := range records {
+		_, _ = w.Write(record)
+		_ = w.WriteByte('\n')
+	}
+
+	err = w.Flush()
+	if err != nil {
+		return errors.Wrap(err, "write file")
+	}
+
+	return errors.Wrap(file.Sync(), "sync file")
+}
+
+// syncDir syncs directory, so renaming of file in it is persisted.
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	return dir.Sync()
+}
@@ -0,0 +1,7 @@
+package scanner
+
+import "errors"
+
+// ErrNotModified is returned by Blog's GetPosts if blog's posts didn't change since last response
+// committed with ResponseCache. Scan iteration is finished successfully without touching storage then.
+var ErrNotModified = errors.New("blog not modified")
@@ -2,6 +2,7 @@ package scanner
 
 import (
 	"context"
+	"time"
 
 	"gbu-scanner/internal/entity"
 )
@@ -14,12 +15,50 @@ type Blog interface {
 	GetPosts(context.Context) ([]entity.Post, error)
 }
 
+// ResponseCache is interface for http cache of Blog's responses.
+// Validators of Blog's last response are committed only after it's posts were processed
+// successfully, otherwise next request could get "not modified" response for never processed posts.
+type ResponseCache interface {
+	// Commit saves validators of responses got since last commit.
+	Commit(ctx context.Context) error
+}
+
+// Enricher is interface for fetching post's content from post's page.
+type Enricher interface {
+	// Enrich returns post with Content fetched from post's url.
+	Enrich(ctx context.Context, post entity.Post) (entity.Post, error)
+}
+
+// Schedule is interface for planning source's scans.
+type Schedule interface {
+	// Next returns time of next scan which is not before now.
+	// It's called from source's goroutine only, so implementation can be stateful.
+	Next(now time.Time) time.Time
+}
+
 // Publisher is interface for interacting with message broker
-// to publish events about new posts.
+// to publish events about new (and edited) posts.
 type Publisher interface {
-	// Publish publishes post to message broker and
+	// Publish publishes event about post to message broker and
 	// other services can process it anyhow.
-	Publish(context.Context, entity.Post) error
+	Publish(context.Context, entity.Event) error
+}
+
+// Outbox is interface for storage of events waiting to be published (transactional outbox).
+// Events are enqueued in the same Posts.Transaction as posts are added (or updated) in
+// published posts, and Relay publishes them with Publisher asynchronously.
+type Outbox interface {
+	// Enqueue adds event to outbox as pending. Enqueuing same event
+	// (same type, post's url and revision) twice is a no-op.
+	Enqueue(ctx context.Context, event entity.Event) error
+	// GetPending returns up to limit pending entries ordered from oldest to newest.
+	GetPending(ctx context.Context, limit int) ([]entity.OutboxEntry, error)
+	// MarkSent marks entry as published, so it's not pending anymore.
+	MarkSent(ctx context.Context, id string) error
+	// MarkFailed increments entry's attempts count and saves error of publishing.
+	MarkFailed(ctx context.Context, id string, reason error) error
+	// CountPending returns count of pending entries.
+	CountPending(ctx context.Context) (int64, error)
 }
 
 // Posts is interface for interacting with storage where
@@ -27,9 +66,13 @@ type Publisher interface {
 // Notice: "published posts" is not same thing as "posted in blog":
 // "Published" means "published to message broker".
 type Posts interface {
+	// Transaction calls fn in transaction, fn must use txCtx for calls to Posts and Outbox.
+	// If fn returns error, transaction is aborted.
 	Transaction(ctx context.Context, fn func(txCtx context.Context) error) error
 	// Add saves post to list of published posts.
 	Add(ctx context.Context, post entity.Post) error
+	// Update replaces published post with same url with new version of post.
+	Update(ctx context.Context, post entity.Post) error
 	// GetAll reutrns all posts published to a message broker.
 	GetAll(ctx context.Context) ([]entity.Post, error)
 }
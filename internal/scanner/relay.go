@@ -0,0 +1,125 @@
+package scanner
+
+import (
+	"context"
+	"time"
+
+	"gbu-scanner/internal/metrics"
+
+	"gbu-scanner/pkg/logger"
+	"gbu-scanner/pkg/sleep"
+
+	"github.com/pkg/errors"
+)
+
+// Relay is struct that publishes posts enqueued to outbox by Scanner.
+// Posts are published strictly in enqueuing order: if publishing fails,
+// relay's iteration stops and the same post is retried next iteration.
+type Relay struct {
+	outbox    Outbox
+	publisher Publisher
+	interval  time.Duration
+	batchSize int
+	log       logger.Logger
+}
+
+// NewRelay returns new relay which drains outbox to publisher with method Run.
+func NewRelay(outbox Outbox, publisher Publisher, interval time.Duration, batchSize int, log logger.Logger) *Relay {
+	return &Relay{
+		outbox:    outbox,
+		publisher: publisher,
+		interval:  interval,
+		batchSize: batchSize,
+		log:       log,
+	}
+}
+
+// Run is a blocking method until context cancelled, it publishes pending
+// outbox's entries in a loop with specified interval (r.interval).
+// Run's current implementation always returns nil-error when context is closed.
+func (r *Relay) Run(ctx context.Context) error {
+	r.log.Info("starting relay")
+
+	for isCtxClosed := false; !isCtxClosed; {
+		sent, err := r.relayIteration(ctx)
+		if err != nil {
+			r.log.Error(errors.Wrap(err, "error during relaying"))
+		}
+
+		// Full batch sent - there are probably more pending entries, no need to wait.
+		if err == nil && sent == r.batchSize {
+			isCtxClosed = ctx.Err() != nil
+			continue
+		}
+
+		isCtxClosed = sleep.WithContext(ctx, r.interval)
+	}
+
+	r.log.Info("relay finished")
+
+	return nil
+}
+
+// Drain publishes pending outbox's entries until there are no more of them or publishing fails.
+func (r *Relay) Drain(ctx context.Context) error {
+	for {
+		sent, err := r.relayIteration(ctx)
+		if err != nil {
+			return err
+		}
+
+		if sent < r.batchSize {
+			return nil
+		}
+	}
+}
+
+// relayIteration called in Run method to reduce it's loop's complexity.
+// Returns count of published entries.
+func (r *Relay) relayIteration(ctx context.Context) (int, error) {
+	entries, err := r.outbox.GetPending(ctx, r.batchSize)
+	if err != nil {
+		return 0, errors.Wrap(err, "get pending entries")
+	}
+
+	if len(entries) == 0 {
+		metrics.OutboxPending.Set(0)
+		return 0, nil
+	}
+
+	pending, err := r.outbox.CountPending(ctx)
+	if err != nil {
+		r.log.Warn(errors.Wrap(err, "can't count pending events in outbox"))
+		pending = int64(len(entries))
+	} else {
+		metrics.OutboxPending.Set(float64(pending))
+	}
+
+	r.log.Infof("publishing %d of %d pending events from outbox", len(entries), pending)
+
+	for i, entry := range entries {
+		post := entry.Event.Post
+		r.log.Infof("publishing %s event of post %q from %q", entry.Event.Type, post.Title, post.Source)
+
+		err = r.publisher.Publish(ctx, entry.Event)
+		if err != nil {
+			err = errors.Wrapf(err, "publish %s event of post %q (attempt #%d)", entry.Event.Type, post.URL, entry.Attempts+1)
+
+			markErr := r.outbox.MarkFailed(ctx, entry.ID, err)
+			if markErr != nil {
+				r.log.Error(errors.Wrap(markErr, "can't mark outbox entry as failed"))
+			}
+
+			return i, err
+		}
+
+		// The saddest story - post published, but can't submit this information, so post will be published again.
+		// It is a problem "at least once / at most once", where I have chosen "at least once".
+		err = r.outbox.MarkSent(ctx, entry.ID)
+		if err != nil {
+			return i, errors.Wrap(err, "mark outbox entry as sent")
+		}
+	}
+
+	return len(entries), nil
+}
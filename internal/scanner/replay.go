@@ -0,0 +1,108 @@
+package scanner
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gbu-scanner/internal/entity"
+
+	"gbu-scanner/pkg/logger"
+	"gbu-scanner/pkg/sleep"
+
+	"github.com/pkg/errors"
+)
+
+// ReplayFilter selects published posts to replay. Empty fields don't filter.
+type ReplayFilter struct {
+	// Since and Until limit post's date (inclusive).
+	Since, Until time.Time
+	// Author is case-insensitive substring of post's author.
+	Author string
+	// URLPattern is regular expression which post's url must match.
+	URLPattern *regexp.Regexp
+	// Source is name of post's source.
+	Source string
+}
+
+// matches returns true if post passes filter.
+func (f ReplayFilter) matches(post entity.Post) bool {
+	switch {
+	case !f.Since.IsZero() && post.Date.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && post.Date.After(f.Until):
+		return false
+	case f.Author != "" && !strings.Contains(strings.ToLower(post.Author), strings.ToLower(f.Author)):
+		return false
+	case f.URLPattern != nil && !f.URLPattern.MatchString(post.URL):
+		return false
+	case f.Source != "" && post.Source != f.Source:
+		return false
+	}
+
+	return true
+}
+
+// Replayer is struct that republishes published posts, so new consumers can get history.
+type Replayer struct {
+	posts     Posts
+	publisher Publisher
+	log       logger.Logger
+}
+
+// NewReplayer returns new replayer which republishes posts from posts with publisher with method Replay.
+func NewReplayer(posts Posts, publisher Publisher, log logger.Logger) *Replayer {
+	return &Replayer{
+		posts:     posts,
+		publisher: publisher,
+		log:       log,
+	}
+}
+
+// Replay republishes published posts passing filter (except removed ones) from oldest to newest as
+// EventPostCreated events marked as replayed. Publishing bypasses outbox. Posts are published with
+// at least interval between them to not flood consumers. Returns count of republished posts.
+func (r *Replayer) Replay(ctx context.Context, filter ReplayFilter, interval time.Duration) (int, error) {
+	posts, err := r.posts.GetAll(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "get published posts")
+	}
+
+	var selected []entity.Post
+	for _, post := range posts {
+		if !post.Removed && filter.matches(post) {
+			selected = append(selected, post)
+		}
+	}
+
+	sort.SliceStable(selected, func(i, j int) bool {
+		return selected[i].Date.Before(selected[j].Date)
+	})
+
+	r.log.Infof("replaying %d of %d published posts", len(selected), len(posts))
+
+	for i, post := range selected {
+		if i != 0 {
+			isCtxClosed := sleep.WithContext(ctx, interval)
+			if isCtxClosed {
+				return i, errors.Wrap(ctx.Err(), "wait before publishing")
+			}
+		}
+
+		r.log.Infof("replaying post %q (%d of %d)", post.URL, i+1, len(selected))
+
+		err = r.publisher.Publish(ctx, entity.Event{
+			Type:       entity.EventPostCreated,
+			OccurredAt: time.Now(),
+			Post:       post,
+			Replay:     true,
+		})
+		if err != nil {
+			return i, errors.Wrapf(err, "publish post %q", post.URL)
+		}
+	}
+
+	return len(selected), nil
+}
@@ -2,9 +2,11 @@ package scanner
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"gbu-scanner/internal/entity"
+	"gbu-scanner/internal/metrics"
 
 	"gbu-scanner/pkg/logger"
 	"gbu-scanner/pkg/sleep"
@@ -12,110 +14,543 @@ import (
 	"github.com/pkg/errors"
 )
 
+// Source is a named blog to scan with it's own schedule.
+type Source struct {
+	// Name is source's name, every post fetched from Blog is tagged with it.
+	Name string
+	// Blog is where posts are fetched from.
+	Blog Blog
+	// Schedule plans Blog's scans.
+	Schedule Schedule
+	// Enricher fetches content of new posts. Optional, nil disables fetching content.
+	Enricher Enricher
+	// Cache is http cache of Blog's responses. Optional, must be set if Blog can return ErrNotModified.
+	Cache ResponseCache
+}
+
+// FirstRunMode is way to handle posts of source without published posts (first scan).
+type FirstRunMode string
+
+const (
+	// FirstRunPublishAll publishes all posts of source.
+	FirstRunPublishAll FirstRunMode = "publish"
+	// FirstRunSeed saves all posts of source to published posts without publishing.
+	FirstRunSeed FirstRunMode = "seed"
+	// FirstRunNewest publishes only FirstRunPolicy.Newest newest posts, others are seeded.
+	FirstRunNewest FirstRunMode = "newest"
+	// FirstRunSince publishes only posts dated since FirstRunPolicy.Since, others are seeded.
+	FirstRunSince FirstRunMode = "since"
+)
+
+// FirstRunPolicy is policy for source without published posts, so first scan doesn't publish
+// whole blog's history. Empty Mode means FirstRunPublishAll.
+type FirstRunPolicy struct {
+	Mode FirstRunMode
+	// Newest is count of posts to publish with FirstRunNewest mode.
+	Newest int
+	// Since is min date of posts to publish with FirstRunSince mode.
+	Since time.Time
+}
+
+// Options are optional scanner's features.
+type Options struct {
+	// DetectUpdates enables comparing published posts with posts from blog
+	// and publishing EventPostUpdated events when post was edited.
+	DetectUpdates bool
+	// RemovalThreshold is count of consecutive successful scans where published post is
+	// missing in blog after which post is considered removed and EventPostRemoved event is
+	// published. Zero disables detection of removed posts.
+	RemovalThreshold int
+	// FirstRun is policy for posts of source without published posts. Posts stored before
+	// sources were introduced (without source) are considered published posts of every source.
+	FirstRun FirstRunPolicy
+}
+
 // Scanner is struct that incapsulates business-logic's dependencies (interfaces) and configuration.
 type Scanner struct {
-	blog      Blog
-	publisher Publisher
-	posts     Posts
-	interval  time.Duration
-	log       logger.Logger
+	sources []Source
+	posts   Posts
+	outbox  Outbox
+	options Options
+	log     logger.Logger
+
+	// lastSuccess is time of last successful scan iteration of each source.
+	lastSuccess map[string]time.Time
+	mu          *sync.RWMutex // Protects lastSuccess.
+
+	// misses is count of consecutive scans where published post was missing in blog
+	// by source's name and post's url. Each source's map is used only in source's goroutine.
+	misses map[string]map[string]int
 }
 
 // New returns new scanner with main business-logic of this service - method Scan.
-func New(blog Blog, publisher Publisher, posts Posts, interval time.Duration, log logger.Logger) *Scanner {
+func New(sources []Source, posts Posts, outbox Outbox, options Options, log logger.Logger) *Scanner {
+	misses := make(map[string]map[string]int, len(sources))
+	for _, source := range sources {
+		misses[source.Name] = make(map[string]int)
+	}
+
 	return &Scanner{
-		blog:      blog,
-		publisher: publisher,
-		posts:     posts,
-		interval:  interval,
-		log:       log,
+		sources: sources,
+		posts:   posts,
+		outbox:  outbox,
+		options: options,
+		log:     log,
+
+		lastSuccess: make(map[string]time.Time, len(sources)),
+		mu:          &sync.RWMutex{},
+
+		misses: misses,
 	}
 }
 
-// Scan is a blocking method until context cancelled, it does blog's posts scanning in a loop.
-// Once new post posted in blog, information about it published to message broker and
+// LastSuccess returns time of last successful scan iteration of each source.
+// Source without successful iterations has zero time.
+func (s *Scanner) LastSuccess() map[string]time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	lastSuccess := make(map[string]time.Time, len(s.sources))
+	for _, source := range s.sources {
+		lastSuccess[source.Name] = s.lastSuccess[source.Name]
+	}
+
+	return lastSuccess
+}
+
+// Scan is a blocking method until context cancelled, it does blogs' posts scanning in a loop.
+// Once new post posted in blog, it's enqueued to outbox, Relay publishes it to message broker and
 // consumers (other services) can do whatever they please with this information.
+// Each source is scanned in it's own goroutine with it's own schedule.
 // Scan's current implementation always returns nil-error when context is closed.
 func (s *Scanner) Scan(ctx context.Context) error {
 	s.log.Info("starting scanning")
 
-	// Loop executes scanning interations with specified inteval (s.interval) until context closed.
-	for isCtxClosed := false; !isCtxClosed; isCtxClosed = sleep.WithContext(ctx, s.interval) {
-		errs := s.scanIteration(ctx)
-		for _, err := range errs {
-			s.log.Error(errors.Wrap(err, "error during scanning"))
-		}
+	wg := &sync.WaitGroup{}
+	for _, source := range s.sources {
+		wg.Add(1)
+		go func(source Source) {
+			defer wg.Done()
+			s.scanSource(ctx, source)
+		}(source)
 	}
+	wg.Wait()
 
 	s.log.Info("scanning finished")
 
 	return nil
 }
 
-// scanIteration called in Scan method to reduce it's loop's complexity.
+// scanSource executes scanning interations of one source planned by source's schedule until context closed.
+func (s *Scanner) scanSource(ctx context.Context, source Source) {
+	for {
+		next := source.Schedule.Next(time.Now())
+		s.log.Infof("next scan of %q is planned at %s", source.Name, next.Format(time.RFC3339))
+
+		isCtxClosed := sleep.WithContext(ctx, time.Until(next))
+		if isCtxClosed {
+			return
+		}
+
+		s.scanAndRecord(ctx, source)
+	}
+}
+
+// ScanOnce does one scan iteration of each source (one by one) and returns. Unlike Scan,
+// it returns error if any source's iteration failed (errors themselves are logged).
+func (s *Scanner) ScanOnce(ctx context.Context) error {
+	var failed int
+	for _, source := range s.sources {
+		if !s.scanAndRecord(ctx, source) {
+			failed++
+		}
+	}
+
+	if failed != 0 {
+		return errors.Errorf("%d of %d blogs scanned with errors", failed, len(s.sources))
+	}
+
+	return nil
+}
+
+// Preview returns events which would be enqueued on next scan iteration of each source
+// without enqueuing them and saving posts. Content of posts is not fetched. As counts of
+// consecutive scans without post are kept in memory, removals are previewed only with
+// RemovalThreshold 1 or after Scan was called.
+func (s *Scanner) Preview(ctx context.Context) ([]entity.Event, error) {
+	publishedPosts, err := s.posts.GetAll(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "get published posts")
+	}
+
+	var events []entity.Event
+	for _, source := range s.sources {
+		posts, err := source.Blog.GetPosts(ctx)
+		if errors.Is(err, ErrNotModified) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "get posts of %q", source.Name)
+		}
+
+		for i := range posts {
+			posts[i].Source = source.Name
+		}
+
+		sourceEvents, _ := s.makeEvents(source, posts, publishedPosts)
+		if isFirstRun(source, publishedPosts) {
+			sourceEvents, _ = s.applyFirstRunPolicy(source, sourceEvents)
+		}
+		events = append(events, sourceEvents...)
+	}
+
+	return events, nil
+}
+
+// scanAndRecord does source's scan iteration, logs it's errors and records it's result
+// to metrics and last success time. Returns true if iteration succeeded.
+func (s *Scanner) scanAndRecord(ctx context.Context, source Source) bool {
+	start := time.Now()
+	errs := s.scanIteration(ctx, source)
+	metrics.ScanDuration.WithLabelValues(source.Name).Observe(time.Since(start).Seconds())
+
+	for _, err := range errs {
+		s.log.Error(errors.Wrapf(err, "error during scanning %q", source.Name))
+	}
+
+	if len(errs) != 0 {
+		metrics.ScanIterations.WithLabelValues(source.Name, metrics.ResultError).Inc()
+		return false
+	}
+
+	metrics.ScanIterations.WithLabelValues(source.Name, metrics.ResultSuccess).Inc()
+
+	s.mu.Lock()
+	s.lastSuccess[source.Name] = time.Now()
+	s.mu.Unlock()
+
+	return true
+}
+
+// scanIteration called in scanSource method to reduce it's loop's complexity.
 // More than one error allowed in iteration so it returns []error.
-func (s *Scanner) scanIteration(ctx context.Context) []error {
+func (s *Scanner) scanIteration(ctx context.Context, source Source) []error {
 	var errs []error
 
-	posts, err := s.blog.GetPosts(ctx)
+	start := time.Now()
+	posts, err := source.Blog.GetPosts(ctx)
+	metrics.FetchDuration.WithLabelValues(source.Name).Observe(time.Since(start).Seconds())
+	if errors.Is(err, ErrNotModified) {
+		s.log.Infof("%q not modified since last scan", source.Name)
+		return nil
+	}
 	if err != nil {
 		return append(errs, errors.Wrap(err, "get posts"))
 	}
 
+	metrics.ParsedPosts.WithLabelValues(source.Name).Set(float64(len(posts)))
+
 	if len(posts) == 0 {
-		s.log.Warn("0 posts")
+		s.log.Warnf("0 posts in %q", source.Name)
 		return nil
 	}
 
-	// Always returns nil, all errors written to errs slice.
-	_ = s.posts.Transaction(ctx, func(txCtx context.Context) error {
-		publihsedPosts, err := s.posts.GetAll(ctx)
+	for i := range posts {
+		posts[i].Source = source.Name
+	}
+
+	if source.Enricher != nil {
+		err = s.enrichNewPosts(ctx, source, posts)
 		if err != nil {
-			errs = append(errs, errors.Wrap(err, "get published posts"))
+			return append(errs, errors.Wrap(err, "enrich new posts"))
 		}
+	}
 
-		var notPublishedPosts []entity.Post
-		for _, p := range posts {
-			isFound := false
+	// Transaction's function can be retried, so events are reset on each call.
+	var events []entity.Event
+	var missing []string
+	var seeded []entity.Post
+	err = s.posts.Transaction(ctx, func(txCtx context.Context) error {
+		publihsedPosts, err := s.posts.GetAll(txCtx)
+		if err != nil {
+			return errors.Wrap(err, "get published posts")
+		}
 
-			for _, pp := range publihsedPosts {
-				if p.URL == pp.URL {
-					isFound = true
-					break
-				}
-			}
+		events, missing = s.makeEvents(source, posts, publihsedPosts)
+		if isFirstRun(source, publihsedPosts) {
+			events, seeded = s.applyFirstRunPolicy(source, events)
+		}
 
-			if !isFound {
-				notPublishedPosts = append(notPublishedPosts, p)
+		// Seeded posts are saved as published without events.
+		for _, post := range seeded {
+			err = s.posts.Add(txCtx, post)
+			if err != nil {
+				return errors.Wrap(err, "add seeded post")
 			}
 		}
 
-		if len(notPublishedPosts) == 0 {
-			s.log.Info("no new posts")
+		if len(events) == 0 {
+			s.log.Infof("no new posts in %q", source.Name)
 			return nil
 		}
 
-		// Publish not published posts from oldest to newest.
-		// (in most cases expected only one not published post per scan iteration).
-		for i := len(notPublishedPosts) - 1; i >= 0; i-- {
-			s.log.Infof("publishing post %q", notPublishedPosts[i].Title)
-
-			err = s.publisher.Publish(ctx, notPublishedPosts[i])
+		// Any error aborts transaction: post is either both enqueued and saved or none of it.
+		for _, event := range events {
+			err = s.enqueueEvent(txCtx, event)
 			if err != nil {
-				errs = append(errs, errors.Wrap(err, "publish post"))
-				continue
-			}
-
-			// The saddest story - post published, but can't submit this information, so post will be published again.
-			// It is a problem "at least once / at most once", where I have chosen "at least once".
-			err = s.posts.Add(ctx, notPublishedPosts[i])
-			if err != nil {
-				errs = append(errs, errors.Wrap(err, "add published post"))
+				return errors.Wrapf(err, "enqueue %s event", event.Type)
 			}
 		}
 
 		return nil
 	})
+	if err != nil {
+		errs = append(errs, errors.Wrap(err, "enqueue events"))
+		return errs
+	}
+
+	s.updateMisses(source, missing, events)
+
+	// Posts are processed, so blog's response can be cached.
+	if source.Cache != nil {
+		err = source.Cache.Commit(ctx)
+		if err != nil {
+			errs = append(errs, errors.Wrap(err, "commit response cache"))
+		}
+	}
+
+	metrics.SeededPosts.WithLabelValues(source.Name).Add(float64(len(seeded)))
+
+	for _, event := range events {
+		switch event.Type {
+		case entity.EventPostCreated:
+			metrics.NewPosts.WithLabelValues(source.Name).Inc()
+		case entity.EventPostUpdated:
+			metrics.UpdatedPosts.WithLabelValues(source.Name).Inc()
+		case entity.EventPostRemoved:
+			metrics.RemovedPosts.WithLabelValues(source.Name).Inc()
+		}
+	}
 
 	return errs
 }
+
+// makeEvents compares posts from blog with published posts and returns events to enqueue ordered
+// from oldest post to newest, so relay publishes them in this order (in most cases expected only one
+// new post per scan iteration). Events are about new posts, edited posts (if Options.DetectUpdates is set)
+// and removed posts (if Options.RemovalThreshold is set) - they go last.
+// It also returns urls of source's published posts missing in blog but not considered removed yet.
+// makeEvents doesn't change scanner's state as it's called in transaction which can be retried.
+func (s *Scanner) makeEvents(source Source, posts, publishedPosts []entity.Post) ([]entity.Event, []string) {
+	publishedByURL := make(map[string]entity.Post, len(publishedPosts))
+	for _, pp := range publishedPosts {
+		publishedByURL[pp.URL] = pp
+	}
+
+	var events []entity.Event
+	for i := len(posts) - 1; i >= 0; i-- {
+		p := posts[i]
+
+		pp, isFound := publishedByURL[p.URL]
+		if !isFound {
+			p.Revision = 1
+			events = append(events, entity.Event{Type: entity.EventPostCreated, Post: p})
+			continue
+		}
+
+		// Posts stored before revisions were introduced have 0 revision, but it's their first revision.
+		p.Revision = pp.Revision + 1
+		if pp.Revision == 0 {
+			p.Revision = 2
+		}
+
+		// Content is fetched only for new posts, new revision keeps content of stored one.
+		if p.Content == nil {
+			p.Content = pp.Content
+		}
+
+		// Removed post appeared in blog again - it's announced as new one.
+		if pp.Removed {
+			events = append(events, entity.Event{Type: entity.EventPostCreated, Post: p})
+			continue
+		}
+
+		if !s.options.DetectUpdates {
+			continue
+		}
+
+		diff := pp.Diff(p)
+		if len(diff) == 0 {
+			continue
+		}
+
+		events = append(events, entity.Event{Type: entity.EventPostUpdated, Post: p, Diff: diff})
+	}
+
+	if s.options.RemovalThreshold == 0 {
+		return events, nil
+	}
+
+	postsURLs := make(map[string]bool, len(posts))
+	for _, p := range posts {
+		postsURLs[p.URL] = true
+	}
+
+	var missing []string
+	for _, pp := range publishedPosts {
+		// Posts stored before sources were introduced have empty source and are never considered removed.
+		if pp.Source != source.Name || pp.Removed || postsURLs[pp.URL] {
+			continue
+		}
+
+		if s.misses[source.Name][pp.URL]+1 < s.options.RemovalThreshold {
+			missing = append(missing, pp.URL)
+			continue
+		}
+
+		pp.Removed = true
+		pp.Revision++
+		if pp.Revision == 1 {
+			pp.Revision = 2
+		}
+		events = append(events, entity.Event{Type: entity.EventPostRemoved, Post: pp})
+	}
+
+	return events, missing
+}
+
+// isFirstRun returns true if there are no published posts of source.
+func isFirstRun(source Source, publishedPosts []entity.Post) bool {
+	for _, pp := range publishedPosts {
+		if pp.Source == source.Name || pp.Source == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// applyFirstRunPolicy splits events of source's first scan (all of them are EventPostCreated,
+// ordered from oldest post to newest) to events to enqueue and posts to seed silently.
+func (s *Scanner) applyFirstRunPolicy(source Source, events []entity.Event) ([]entity.Event, []entity.Post) {
+	var published []entity.Event
+	var seeded []entity.Post
+	for i, event := range events {
+		if s.isPublishedOnFirstRun(event.Post, len(events)-1-i) {
+			published = append(published, event)
+		} else {
+			seeded = append(seeded, event.Post)
+		}
+	}
+
+	if len(seeded) != 0 {
+		s.log.Infof("first scan of %q: publishing %d posts, saving %d older posts as published without publishing",
+			source.Name, len(published), len(seeded))
+	}
+
+	return published, seeded
+}
+
+// isPublishedOnFirstRun returns true if post should be published (not seeded) on source's
+// first scan according to first run policy. newerCount is count of posts newer than post.
+func (s *Scanner) isPublishedOnFirstRun(post entity.Post, newerCount int) bool {
+	policy := s.options.FirstRun
+
+	switch policy.Mode {
+	case FirstRunSeed:
+		return false
+	case FirstRunNewest:
+		return newerCount < policy.Newest
+	case FirstRunSince:
+		return !post.Date.Before(policy.Since)
+	default:
+		return true
+	}
+}
+
+// enrichNewPosts fetches content of posts which are not published yet with source's Enricher.
+// It's done before transaction to not make network requests inside it, so published posts are
+// read twice. If content of post can't be fetched, error is logged and post is published without it.
+// On source's first scan content of posts which are seeded by first run policy is not fetched.
+func (s *Scanner) enrichNewPosts(ctx context.Context, source Source, posts []entity.Post) error {
+	publishedPosts, err := s.posts.GetAll(ctx)
+	if err != nil {
+		return errors.Wrap(err, "get published posts")
+	}
+
+	isPublished := make(map[string]bool, len(publishedPosts))
+	for _, pp := range publishedPosts {
+		isPublished[pp.URL] = true
+	}
+
+	// Posts are ordered from newest to oldest, so index is count of newer posts.
+	isFirstRun := isFirstRun(source, publishedPosts)
+
+	for i := range posts {
+		if isPublished[posts[i].URL] {
+			continue
+		}
+
+		if isFirstRun && !s.isPublishedOnFirstRun(posts[i], i) {
+			continue
+		}
+
+		enriched, err := source.Enricher.Enrich(ctx, posts[i])
+		if err != nil {
+			metrics.EnrichFailures.WithLabelValues(source.Name).Inc()
+			s.log.Error(errors.Wrapf(err, "can't fetch content of post %q", posts[i].URL))
+			continue
+		}
+
+		posts[i] = enriched
+	}
+
+	return nil
+}
+
+// updateMisses updates counts of consecutive scans of source where published posts were missing.
+// Counts of posts which are in blog or considered removed are reset.
+func (s *Scanner) updateMisses(source Source, missing []string, events []entity.Event) {
+	misses := s.misses[source.Name]
+
+	isMissing := make(map[string]bool, len(missing))
+	for _, url := range missing {
+		isMissing[url] = true
+		misses[url]++
+
+		s.log.Warnf("post %q is missing in %q (%d of %d scans to be considered removed)",
+			url, source.Name, misses[url], s.options.RemovalThreshold)
+	}
+
+	for url := range misses {
+		if !isMissing[url] {
+			delete(misses, url)
+		}
+	}
+}
+
+// enqueueEvent enqueues event to outbox and saves event's post to published posts.
+// Post of first revision is added to published posts, others replace stored post.
+func (s *Scanner) enqueueEvent(txCtx context.Context, event entity.Event) error {
+	s.log.Infof("enqueuing %s event of post %q from %q", event.Type, event.Post.Title, event.Post.Source)
+
+	event.OccurredAt = time.Now()
+	err := s.outbox.Enqueue(txCtx, event)
+	if err != nil {
+		return errors.Wrap(err, "enqueue event")
+	}
+
+	if event.Post.Revision == 1 {
+		err = s.posts.Add(txCtx, event.Post)
+		if err != nil {
+			return errors.Wrap(err, "add published post")
+		}
+		return nil
+	}
+
+	err = s.posts.Update(txCtx, event.Post)
+	if err != nil {
+		return errors.Wrap(err, "update published post")
+	}
+
+	return nil
+}
@@ -0,0 +1,17 @@
+package topology
+
+// Binding's destination types.
+const (
+	DestinationQueue    = "queue"
+	DestinationExchange = "exchange"
+)
+
+// queueTypeArgument is queue's argument with it's type, rabbit sets it to
+// defaultQueueType if it's not declared.
+const (
+	queueTypeArgument = "x-queue-type"
+	defaultQueueType  = "classic"
+)
+
+// defaultVhost is rabbit's vhost used if it's not specified.
+const defaultVhost = "/"
@@ -0,0 +1,195 @@
+package topology
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// ChangeKind is kind of difference between declared and actual topologies.
+type ChangeKind string
+
+const (
+	// ChangeMissing is entity declared in file, but absent in broker.
+	ChangeMissing ChangeKind = "missing"
+	// ChangeDiffers is entity existing in broker with other parameters than declared.
+	// Applying such topology fails, entity must be deleted from broker first.
+	ChangeDiffers ChangeKind = "differs"
+	// ChangeUndeclared is binding of declared exchange existing in broker, but absent in file.
+	// Only bindings are reported, as vhost can have other services' exchanges and queues.
+	ChangeUndeclared ChangeKind = "undeclared"
+)
+
+// Change is difference of one entity between declared and actual topologies.
+type Change struct {
+	Kind ChangeKind `json:"kind"`
+	// Entity is entity's description, e.g. `queue "telegram-bot"`.
+	Entity string `json:"entity"`
+	// Details are differing parameters, only for ChangeDiffers.
+	Details []string `json:"details,omitempty"`
+}
+
+// Diff returns changes which should be made to actual topology to get declared one.
+func Diff(declared, actual Topology) []Change {
+	var changes []Change
+
+	actualExchanges := make(map[string]Exchange, len(actual.Exchanges))
+	for _, e := range actual.Exchanges {
+		actualExchanges[e.Name] = e
+	}
+
+	for _, e := range declared.Exchanges {
+		entity := fmt.Sprintf("exchange %q", e.Name)
+
+		a, ok := actualExchanges[e.Name]
+		if !ok {
+			changes = append(changes, Change{Kind: ChangeMissing, Entity: entity})
+			continue
+		}
+
+		var details []string
+		details = compare(details, "type", e.Type, a.Type)
+		details = compare(details, "durable", isDurable(e.Durable), isDurable(a.Durable))
+		details = compare(details, "autoDelete", e.AutoDelete, a.AutoDelete)
+		details = compare(details, "internal", e.Internal, a.Internal)
+		details = compareArguments(details, e.Arguments, a.Arguments)
+
+		if len(details) != 0 {
+			changes = append(changes, Change{Kind: ChangeDiffers, Entity: entity, Details: details})
+		}
+	}
+
+	actualQueues := make(map[string]Queue, len(actual.Queues))
+	for _, q := range actual.Queues {
+		actualQueues[q.Name] = q
+	}
+
+	for _, q := range declared.Queues {
+		entity := fmt.Sprintf("queue %q", q.Name)
+
+		a, ok := actualQueues[q.Name]
+		if !ok {
+			changes = append(changes, Change{Kind: ChangeMissing, Entity: entity})
+			continue
+		}
+
+		var details []string
+		details = compare(details, "durable", isDurable(q.Durable), isDurable(a.Durable))
+		details = compare(details, "autoDelete", q.AutoDelete, a.AutoDelete)
+		details = compareArguments(details, q.Arguments, a.Arguments)
+
+		if len(details) != 0 {
+			changes = append(changes, Change{Kind: ChangeDiffers, Entity: entity, Details: details})
+		}
+	}
+
+	return append(changes, diffBindings(declared, actual)...)
+}
+
+// diffBindings returns missing bindings, bindings with other arguments
+// and undeclared bindings of declared exchanges.
+func diffBindings(declared, actual Topology) []Change {
+	var changes []Change
+
+	actualBindings := make(map[string]Binding, len(actual.Bindings))
+	for _, b := range actual.Bindings {
+		actualBindings[b.String()] = b
+	}
+
+	declaredBindings := make(map[string]bool, len(declared.Bindings))
+	for _, b := range declared.Bindings {
+		declaredBindings[b.String()] = true
+
+		a, ok := actualBindings[b.String()]
+		if !ok {
+			changes = append(changes, Change{Kind: ChangeMissing, Entity: b.String()})
+			continue
+		}
+
+		details := compareArguments(nil, b.Arguments, a.Arguments)
+		if len(details) != 0 {
+			changes = append(changes, Change{Kind: ChangeDiffers, Entity: b.String(), Details: details})
+		}
+	}
+
+	declaredExchanges := make(map[string]bool, len(declared.Exchanges))
+	for _, e := range declared.Exchanges {
+		declaredExchanges[e.Name] = true
+	}
+
+	for _, b := range actual.Bindings {
+		if declaredExchanges[b.Source] && !declaredBindings[b.String()] {
+			changes = append(changes, Change{Kind: ChangeUndeclared, Entity: b.String()})
+		}
+	}
+
+	return changes
+}
+
+// String returns binding's description, which identifies it.
+func (b Binding) String() string {
+	return fmt.Sprintf("binding of %s %q to exchange %q with key %q", b.DestinationType, b.Destination, b.Source, b.RoutingKey)
+}
+
+// compare appends description of difference to details if declared and actual values differ.
+func compare(details []string, name string, declared, actual interface{}) []string {
+	if declared == actual {
+		return details
+	}
+
+	return append(details, fmt.Sprintf("%s: declared %v, actual %v", name, declared, actual))
+}
+
+// compareArguments appends descriptions of differing arguments to details.
+// Arguments are compared as JSON values, as numbers decoded from YAML and
+// from management API's response have different types.
+func compareArguments(details []string, declared, actual map[string]interface{}) []string {
+	keys := make(map[string]bool, len(declared)+len(actual))
+	for key := range declared {
+		keys[key] = true
+	}
+	for key := range actual {
+		keys[key] = true
+	}
+
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	for _, key := range sorted {
+		declaredValue, isDeclared := declared[key]
+		actualValue, isActual := actual[key]
+
+		switch {
+		case !isDeclared && key == queueTypeArgument && actualValue == defaultQueueType:
+			// Rabbit sets queue's type even if it's not declared.
+		case !isDeclared:
+			details = append(details, fmt.Sprintf("argument %s: not declared, actual %v", key, actualValue))
+		case !isActual:
+			details = append(details, fmt.Sprintf("argument %s: declared %v, not set", key, declaredValue))
+		case !reflect.DeepEqual(normalize(declaredValue), normalize(actualValue)):
+			details = append(details, fmt.Sprintf("argument %s: declared %v, actual %v", key, declaredValue, actualValue))
+		}
+	}
+
+	return details
+}
+
+// normalize returns value as it's decoded from JSON.
+func normalize(value interface{}) interface{} {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+
+	var normalized interface{}
+	err = json.Unmarshal(data, &normalized)
+	if err != nil {
+		return value
+	}
+
+	return normalized
+}
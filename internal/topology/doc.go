@@ -0,0 +1,4 @@
+// Package topology provides declarative description of rabbit's topology (exchanges,
+// queues and bindings) loaded from YAML or JSON file. Topology is declared on
+// channel with Apply and can be compared with broker's actual topology with Diff.
+package topology
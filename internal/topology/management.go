@@ -0,0 +1,152 @@
+package topology
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ManagementClient gets broker's actual topology with rabbit's management HTTP API.
+type ManagementClient struct {
+	baseURL    string
+	user, pass string
+	vhost      string
+	client     *http.Client
+}
+
+// NewManagementClient returns client of management API available at baseURL (e.g. "http://localhost:15672").
+// Empty vhost is default vhost "/".
+func NewManagementClient(baseURL, user, pass, vhost string, client *http.Client) *ManagementClient {
+	if vhost == "" {
+		vhost = defaultVhost
+	}
+
+	return &ManagementClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		user:    user,
+		pass:    pass,
+		vhost:   vhost,
+		client:  client,
+	}
+}
+
+// managementExchange is exchange in management API's response.
+type managementExchange struct {
+	Name       string                 `json:"name"`
+	Type       string                 `json:"type"`
+	Durable    bool                   `json:"durable"`
+	AutoDelete bool                   `json:"auto_delete"`
+	Internal   bool                   `json:"internal"`
+	Arguments  map[string]interface{} `json:"arguments"`
+}
+
+// managementQueue is queue in management API's response.
+type managementQueue struct {
+	Name       string                 `json:"name"`
+	Durable    bool                   `json:"durable"`
+	AutoDelete bool                   `json:"auto_delete"`
+	Arguments  map[string]interface{} `json:"arguments"`
+}
+
+// managementBinding is binding in management API's response.
+type managementBinding struct {
+	Source          string                 `json:"source"`
+	Destination     string                 `json:"destination"`
+	DestinationType string                 `json:"destination_type"`
+	RoutingKey      string                 `json:"routing_key"`
+	Arguments       map[string]interface{} `json:"arguments"`
+}
+
+// Get returns topology of client's vhost. Default exchange ("") and it's implicit
+// bindings are skipped, as they can't be declared.
+func (c *ManagementClient) Get(ctx context.Context) (Topology, error) {
+	var exchanges []managementExchange
+	err := c.get(ctx, "exchanges", &exchanges)
+	if err != nil {
+		return Topology{}, errors.Wrap(err, "get exchanges")
+	}
+
+	var queues []managementQueue
+	err = c.get(ctx, "queues", &queues)
+	if err != nil {
+		return Topology{}, errors.Wrap(err, "get queues")
+	}
+
+	var bindings []managementBinding
+	err = c.get(ctx, "bindings", &bindings)
+	if err != nil {
+		return Topology{}, errors.Wrap(err, "get bindings")
+	}
+
+	var topology Topology
+
+	for _, e := range exchanges {
+		if e.Name == "" {
+			continue
+		}
+
+		durable := e.Durable
+		topology.Exchanges = append(topology.Exchanges, Exchange{
+			Name:       e.Name,
+			Type:       e.Type,
+			Durable:    &durable,
+			AutoDelete: e.AutoDelete,
+			Internal:   e.Internal,
+			Arguments:  e.Arguments,
+		})
+	}
+
+	for _, q := range queues {
+		durable := q.Durable
+		topology.Queues = append(topology.Queues, Queue{
+			Name:       q.Name,
+			Durable:    &durable,
+			AutoDelete: q.AutoDelete,
+			Arguments:  q.Arguments,
+		})
+	}
+
+	for _, b := range bindings {
+		if b.Source == "" {
+			continue
+		}
+
+		topology.Bindings = append(topology.Bindings, Binding{
+			Source:          b.Source,
+			Destination:     b.Destination,
+			DestinationType: b.DestinationType,
+			RoutingKey:      b.RoutingKey,
+			Arguments:       b.Arguments,
+		})
+	}
+
+	return topology, nil
+}
+
+// get gets list of entities of vhost and decodes it into v.
+func (c *ManagementClient) get(ctx context.Context, entities string, v interface{}) error {
+	endpoint := fmt.Sprintf("%s/api/%s/%s", c.baseURL, entities, url.PathEscape(c.vhost))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return errors.Wrap(err, "make request")
+	}
+	req.SetBasicAuth(c.user, c.pass)
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "do request")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected response status %s", res.Status)
+	}
+
+	return errors.Wrap(json.NewDecoder(res.Body).Decode(v), "decode response")
+}
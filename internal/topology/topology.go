@@ -0,0 +1,195 @@
+package topology
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/streadway/amqp"
+	"gopkg.in/yaml.v3"
+)
+
+// Topology is set of rabbit's entities declared by publisher.
+type Topology struct {
+	Exchanges []Exchange `yaml:"exchanges"`
+	Queues    []Queue    `yaml:"queues"`
+	Bindings  []Binding  `yaml:"bindings"`
+}
+
+// Exchange is description of rabbit's exchange.
+type Exchange struct {
+	Name string `yaml:"name"`
+	// Type is "fanout", "topic", "direct" or "headers".
+	Type string `yaml:"type"`
+	// Durable is true by default.
+	Durable    *bool                  `yaml:"durable"`
+	AutoDelete bool                   `yaml:"autoDelete"`
+	Internal   bool                   `yaml:"internal"`
+	Arguments  map[string]interface{} `yaml:"arguments"`
+}
+
+// Queue is description of rabbit's queue. Dead-letter exchanges,
+// TTLs etc. are set with arguments (e.g. "x-message-ttl").
+type Queue struct {
+	Name string `yaml:"name"`
+	// Durable is true by default.
+	Durable    *bool                  `yaml:"durable"`
+	AutoDelete bool                   `yaml:"autoDelete"`
+	Arguments  map[string]interface{} `yaml:"arguments"`
+}
+
+// Binding is description of binding of queue or exchange to exchange.
+type Binding struct {
+	// Source is exchange's name.
+	Source      string `yaml:"source"`
+	Destination string `yaml:"destination"`
+	// DestinationType is DestinationQueue (default) or DestinationExchange.
+	DestinationType string                 `yaml:"destinationType"`
+	RoutingKey      string                 `yaml:"routingKey"`
+	Arguments       map[string]interface{} `yaml:"arguments"`
+}
+
+// Load reads topology from YAML or JSON file (JSON is subset of YAML) and validates it.
+func Load(path string) (Topology, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Topology{}, errors.Wrap(err, "read file")
+	}
+
+	var topology Topology
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	err = decoder.Decode(&topology)
+	if err != nil {
+		return Topology{}, errors.Wrap(err, "decode topology")
+	}
+
+	topology.setDefaults()
+
+	err = topology.validate()
+	if err != nil {
+		return Topology{}, errors.Wrap(err, "invalid topology")
+	}
+
+	return topology, nil
+}
+
+// setDefaults sets default values of omitted fields.
+func (t *Topology) setDefaults() {
+	for i := range t.Bindings {
+		if t.Bindings[i].DestinationType == "" {
+			t.Bindings[i].DestinationType = DestinationQueue
+		}
+	}
+}
+
+// validate returns error if some required field is empty or has unknown value.
+func (t Topology) validate() error {
+	for i, exchange := range t.Exchanges {
+		if exchange.Name == "" {
+			return errors.Errorf("exchange #%d has no name", i+1)
+		}
+
+		switch exchange.Type {
+		case amqp.ExchangeDirect, amqp.ExchangeFanout, amqp.ExchangeTopic, amqp.ExchangeHeaders:
+		default:
+			return errors.Errorf("exchange %q has unknown type %q", exchange.Name, exchange.Type)
+		}
+	}
+
+	for i, queue := range t.Queues {
+		if queue.Name == "" {
+			return errors.Errorf("queue #%d has no name", i+1)
+		}
+	}
+
+	for i, binding := range t.Bindings {
+		if binding.Source == "" || binding.Destination == "" {
+			return errors.Errorf("binding #%d has no source or destination", i+1)
+		}
+
+		if binding.DestinationType != DestinationQueue && binding.DestinationType != DestinationExchange {
+			return errors.Errorf("binding #%d has unknown destination type %q", i+1, binding.DestinationType)
+		}
+	}
+
+	return nil
+}
+
+// Apply declares topology's exchanges, queues and bindings on channel. Declaring is
+// idempotent, but if entity already exists with other parameters, rabbit closes channel.
+func (t Topology) Apply(ch *amqp.Channel) error {
+	for _, e := range t.Exchanges {
+		err := ch.ExchangeDeclare(e.Name, e.Type, isDurable(e.Durable), e.AutoDelete, e.Internal, false, makeTable(e.Arguments))
+		if err != nil {
+			return errors.Wrapf(err, "declare exchange %q", e.Name)
+		}
+	}
+
+	for _, q := range t.Queues {
+		_, err := ch.QueueDeclare(q.Name, isDurable(q.Durable), q.AutoDelete, false, false, makeTable(q.Arguments))
+		if err != nil {
+			return errors.Wrapf(err, "declare queue %q", q.Name)
+		}
+	}
+
+	for _, b := range t.Bindings {
+		var err error
+		if b.DestinationType == DestinationExchange {
+			err = ch.ExchangeBind(b.Destination, b.RoutingKey, b.Source, false, makeTable(b.Arguments))
+		} else {
+			err = ch.QueueBind(b.Destination, b.RoutingKey, b.Source, false, makeTable(b.Arguments))
+		}
+		if err != nil {
+			return errors.Wrapf(err, "bind %s %q to exchange %q", b.DestinationType, b.Destination, b.Source)
+		}
+	}
+
+	return nil
+}
+
+// HasExchange returns true if exchange with name is declared in topology.
+func (t Topology) HasExchange(name string) bool {
+	for _, exchange := range t.Exchanges {
+		if exchange.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isDurable returns durable flag, which is true if it's omitted.
+func isDurable(durable *bool) bool {
+	return durable == nil || *durable
+}
+
+// makeTable converts arguments decoded from YAML to amqp.Table.
+func makeTable(arguments map[string]interface{}) amqp.Table {
+	if arguments == nil {
+		return nil
+	}
+
+	table := make(amqp.Table, len(arguments))
+	for key, value := range arguments {
+		table[key] = makeTableValue(value)
+	}
+
+	return table
+}
+
+// makeTableValue converts nested maps and lists to types supported by amqp.Table.
+func makeTableValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		return makeTable(value)
+	case []interface{}:
+		list := make([]interface{}, len(value))
+		for i, item := range value {
+			list[i] = makeTableValue(item)
+		}
+		return list
+	default:
+		return value
+	}
+}
@@ -0,0 +1,17 @@
+package webhook
+
+import "time"
+
+// Config is configuration of webhook publisher.
+type Config struct {
+	// URLs are endpoints where events are POSTed.
+	URLs []string
+	// Secret is key of HMAC-SHA256 signature of requests.
+	Secret string
+	// MaxAttempts is max count of delivery attempts to one endpoint including first one.
+	MaxAttempts int
+	// BaseDelay is delay before first retry, each next delay is doubled.
+	BaseDelay time.Duration
+	// MaxDelay caps delay between attempts.
+	MaxDelay time.Duration
+}
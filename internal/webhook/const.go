@@ -0,0 +1,22 @@
+package webhook
+
+// Request's headers.
+const (
+	// headerEvent is event's type, e.g. "post.created".
+	headerEvent = "X-GBU-Event"
+	// headerDelivery is event's id, it's the same for each delivery attempt.
+	headerDelivery = "X-GBU-Delivery"
+	// headerTimestamp is unix time (in seconds) when request was signed.
+	headerTimestamp = "X-GBU-Timestamp"
+	// headerSignature is "sha256=" followed by hex of HMAC-SHA256 of "<timestamp>.<body>".
+	headerSignature = "X-GBU-Signature"
+)
+
+// signaturePrefix is prefix of signature's header value with name of hash function.
+const signaturePrefix = "sha256="
+
+// contentTypeJSON is content type of requests' bodies.
+const contentTypeJSON = "application/json"
+
+// errorBodyLimit is max count of response's body bytes included into error.
+const errorBodyLimit = 256
@@ -0,0 +1,4 @@
+// Package webhook provides implementation for scanner.Publisher interface
+// for consumers which can't speak AMQP - it POSTs events as JSON to configured
+// HTTP endpoints, signing bodies with HMAC-SHA256.
+package webhook
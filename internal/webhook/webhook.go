@@ -0,0 +1,273 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gbu-scanner/internal/entity"
+	"gbu-scanner/internal/metrics"
+	"gbu-scanner/internal/scanner"
+
+	"gbu-scanner/pkg/logger"
+	"gbu-scanner/pkg/sleep"
+
+	"github.com/pkg/errors"
+)
+
+// Publisher is implementation for scanner.Publisher interface via HTTP webhooks.
+type Publisher struct {
+	cfg    Config
+	client *http.Client
+	log    logger.Logger
+
+	statuses map[string]*EndpointStatus // By endpoint's url.
+	// delivered are endpoints which already got event which wasn't delivered to all
+	// endpoints (by event's id), they are skipped when event is published again.
+	delivered map[string]map[string]bool
+	mu        *sync.Mutex // Protects statuses and delivered.
+}
+
+var _ scanner.Publisher = &Publisher{}
+
+// EndpointStatus is delivery status of one endpoint.
+type EndpointStatus struct {
+	// Endpoint is endpoint's url without credentials and query.
+	Endpoint string `json:"endpoint"`
+	// Delivered and Failed are counts of delivered events and events
+	// which couldn't be delivered after all attempts.
+	Delivered int `json:"delivered"`
+	Failed    int `json:"failed"`
+	// ConsecutiveFailures is count of failed deliveries since last successful one.
+	ConsecutiveFailures int       `json:"consecutiveFailures"`
+	LastSuccess         time.Time `json:"lastSuccess"`
+	LastFailure         time.Time `json:"lastFailure"`
+	LastError           string    `json:"lastError,omitempty"`
+}
+
+// New returns scanner.Publisher implementation which POSTs events with client
+// to cfg.URLs. Client should have timeout.
+func New(cfg Config, client *http.Client, log logger.Logger) *Publisher {
+	statuses := make(map[string]*EndpointStatus, len(cfg.URLs))
+	for _, endpointURL := range cfg.URLs {
+		statuses[endpointURL] = &EndpointStatus{Endpoint: redact(endpointURL)}
+	}
+
+	return &Publisher{
+		cfg:    cfg,
+		client: client,
+		log:    log,
+
+		statuses:  statuses,
+		delivered: make(map[string]map[string]bool),
+		mu:        &sync.Mutex{},
+	}
+}
+
+// Publish POSTs event as JSON to each endpoint, retrying failed deliveries with exponential
+// backoff. Request is failed if response's status is not 2xx. If event isn't delivered to some
+// endpoints, error is returned and endpoints which got it are skipped when it's published again.
+func (p *Publisher) Publish(ctx context.Context, event entity.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "encode event")
+	}
+
+	var failures []string
+	for _, endpointURL := range p.cfg.URLs {
+		if p.isDelivered(event.ID, endpointURL) {
+			continue
+		}
+
+		err = p.deliver(ctx, endpointURL, event, body)
+		p.record(endpointURL, err)
+		if err != nil {
+			failures = append(failures, errors.Wrap(err, redact(endpointURL)).Error())
+			continue
+		}
+
+		p.markDelivered(event.ID, endpointURL)
+	}
+
+	if len(failures) != 0 {
+		return errors.Errorf("delivery failed to %d of %d endpoints: %s",
+			len(failures), len(p.cfg.URLs), strings.Join(failures, "; "))
+	}
+
+	p.forget(event.ID)
+
+	return nil
+}
+
+// Statuses returns delivery statuses of endpoints in configured order.
+func (p *Publisher) Statuses() []EndpointStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	statuses := make([]EndpointStatus, 0, len(p.cfg.URLs))
+	for _, endpointURL := range p.cfg.URLs {
+		statuses = append(statuses, *p.statuses[endpointURL])
+	}
+
+	return statuses
+}
+
+// deliver sends event to endpoint until success, Config.MaxAttempts attempts or ctx closing.
+func (p *Publisher) deliver(ctx context.Context, endpointURL string, event entity.Event, body []byte) error {
+	endpoint := redact(endpointURL)
+
+	delay := p.cfg.BaseDelay
+	for attempt := 1; ; attempt++ {
+		err := p.send(ctx, endpointURL, event, body)
+		if err == nil || attempt >= p.cfg.MaxAttempts {
+			return err
+		}
+
+		p.log.Warn(errors.Wrapf(err, "can't deliver %s event of post %q to %s (attempt #%d), retrying in %s",
+			event.Type, event.Post.URL, endpoint, attempt, delay))
+		metrics.WebhookRetries.WithLabelValues(endpoint).Inc()
+
+		isCtxClosed := sleep.WithContext(ctx, delay)
+		if isCtxClosed {
+			return errors.Wrap(err, "context closed before retry")
+		}
+
+		delay *= 2
+		if delay > p.cfg.MaxDelay {
+			delay = p.cfg.MaxDelay
+		}
+	}
+}
+
+// send makes one delivery attempt. Each attempt is signed with current time.
+func (p *Publisher) send(ctx context.Context, endpointURL string, event entity.Event, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpointURL, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(redactError(err, endpointURL), "make request")
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req.Header.Set("Content-Type", contentTypeJSON)
+	req.Header.Set(headerEvent, string(event.Type))
+	req.Header.Set(headerDelivery, event.ID)
+	req.Header.Set(headerTimestamp, timestamp)
+	req.Header.Set(headerSignature, signaturePrefix+Sign(p.cfg.Secret, timestamp, body))
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return errors.Wrap(redactError(err, endpointURL), "do request")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		snippet, _ := ioutil.ReadAll(io.LimitReader(res.Body, errorBodyLimit))
+		return errors.Errorf("unexpected response status %s: %s", res.Status, snippet)
+	}
+
+	// Body is drained to reuse connection.
+	_, _ = io.Copy(ioutil.Discard, res.Body)
+
+	return nil
+}
+
+// Sign returns hex of HMAC-SHA256 of "<timestamp>.<body>" with secret. Consumers
+// should compare it with signature's header (without "sha256=" prefix) using
+// hmac.Equal and reject requests with old timestamps to prevent replay.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// record updates endpoint's status and metrics with delivery's result.
+func (p *Publisher) record(endpointURL string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	status := p.statuses[endpointURL]
+
+	if err != nil {
+		status.Failed++
+		status.ConsecutiveFailures++
+		status.LastFailure = time.Now()
+		status.LastError = err.Error()
+		metrics.WebhookDeliveries.WithLabelValues(status.Endpoint, metrics.ResultError).Inc()
+		return
+	}
+
+	status.Delivered++
+	status.ConsecutiveFailures = 0
+	status.LastSuccess = time.Now()
+	metrics.WebhookDeliveries.WithLabelValues(status.Endpoint, metrics.ResultSuccess).Inc()
+}
+
+// isDelivered returns true if event was delivered to endpoint by previous Publish call.
+// Events without id are never considered delivered.
+func (p *Publisher) isDelivered(eventID, endpointURL string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return eventID != "" && p.delivered[eventID][endpointURL]
+}
+
+// markDelivered remembers that event was delivered to endpoint.
+func (p *Publisher) markDelivered(eventID, endpointURL string) {
+	if eventID == "" {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.delivered[eventID] == nil {
+		p.delivered[eventID] = make(map[string]bool)
+	}
+	p.delivered[eventID][endpointURL] = true
+}
+
+// forget forgets event delivered to all endpoints.
+func (p *Publisher) forget(eventID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.delivered, eventID)
+}
+
+// redact returns url without credentials, query and fragment, as they can contain secrets.
+// It's used in logs, metrics and statuses.
+func redact(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "invalid url"
+	}
+
+	u.User, u.RawQuery, u.Fragment = nil, "", ""
+
+	return u.String()
+}
+
+// redactError redacts url in *url.Error returned by http client, as error's
+// text gets to logs, statuses and outbox.
+func redactError(err error, endpointURL string) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = redact(endpointURL)
+	}
+
+	return err
+}
@@ -0,0 +1,137 @@
+package webhook
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gbu-scanner/internal/entity"
+
+	"gbu-scanner/pkg/logger"
+)
+
+// endpoint is test server counting requests and responding with statuses in order
+// (last status is repeated).
+type endpoint struct {
+	t        *testing.T
+	server   *httptest.Server
+	statuses []int
+
+	mu   sync.Mutex
+	hits int
+}
+
+func newEndpoint(t *testing.T, statuses ...int) *endpoint {
+	e := &endpoint{t: t, statuses: statuses}
+	e.server = httptest.NewServer(http.HandlerFunc(e.handle))
+	t.Cleanup(e.server.Close)
+
+	return e
+}
+
+func (e *endpoint) handle(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		e.t.Errorf("read body: %v", err)
+	}
+
+	signature := signaturePrefix + Sign(testSecret, r.Header.Get(headerTimestamp), body)
+	if r.Header.Get(headerSignature) != signature {
+		e.t.Errorf("signature is %q, expected %q", r.Header.Get(headerSignature), signature)
+	}
+	if r.Header.Get(headerEvent) != string(entity.EventPostCreated) || r.Header.Get(headerDelivery) != testEventID {
+		e.t.Errorf("unexpected event headers: %v", r.Header)
+	}
+
+	e.mu.Lock()
+	status := e.statuses[len(e.statuses)-1]
+	if e.hits < len(e.statuses) {
+		status = e.statuses[e.hits]
+	}
+	e.hits++
+	e.mu.Unlock()
+
+	w.WriteHeader(status)
+}
+
+func (e *endpoint) getHits() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.hits
+}
+
+const (
+	testSecret  = "secret"
+	testEventID = "event-1"
+)
+
+func TestPublish(t *testing.T) {
+	// ok fails first attempt and succeeds on retry, bad always fails.
+	ok := newEndpoint(t, http.StatusInternalServerError, http.StatusOK)
+	bad := newEndpoint(t, http.StatusBadRequest)
+
+	publisher := New(Config{
+		URLs:        []string{ok.server.URL, bad.server.URL + "?token=hidden"},
+		Secret:      testSecret,
+		MaxAttempts: 2,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    time.Millisecond,
+	}, &http.Client{Timeout: time.Second}, logger.NewNop())
+
+	event := entity.Event{
+		ID:   testEventID,
+		Type: entity.EventPostCreated,
+		Post: entity.Post{Title: "Go 1.17 is released", URL: "https://go.dev/blog/go1.17"},
+	}
+
+	err := publisher.Publish(context.Background(), event)
+	if err == nil {
+		t.Fatal("expected error of delivery to failing endpoint")
+	}
+	if strings.Contains(err.Error(), "hidden") {
+		t.Errorf("error contains endpoint's query: %v", err)
+	}
+	if ok.getHits() != 2 || bad.getHits() != 2 {
+		t.Fatalf("endpoints got %d and %d requests, expected 2 and 2", ok.getHits(), bad.getHits())
+	}
+
+	// Endpoint which already got event is skipped.
+	_ = publisher.Publish(context.Background(), event)
+	if ok.getHits() != 2 || bad.getHits() != 4 {
+		t.Fatalf("endpoints got %d and %d requests after second publish, expected 2 and 4", ok.getHits(), bad.getHits())
+	}
+
+	statuses := publisher.Statuses()
+	if statuses[0].Delivered != 1 || statuses[1].Failed != 2 {
+		t.Errorf("unexpected statuses: %+v", statuses)
+	}
+}
+
+func TestPublishRedactsClientError(t *testing.T) {
+	// Server is closed, so client returns *url.Error with request's url.
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	publisher := New(Config{
+		URLs:        []string{strings.Replace(server.URL, "http://", "http://user:password@", 1) + "/hook?token=hidden"},
+		MaxAttempts: 1,
+	}, &http.Client{Timeout: time.Second}, logger.NewNop())
+
+	err := publisher.Publish(context.Background(), entity.Event{Type: entity.EventPostCreated})
+	if err == nil {
+		t.Fatal("expected error of delivery to closed server")
+	}
+
+	lastError := publisher.Statuses()[0].LastError
+	for _, text := range []string{err.Error(), lastError} {
+		if strings.Contains(text, "hidden") || strings.Contains(text, "password") {
+			t.Errorf("error contains endpoint's secrets: %s", text)
+		}
+	}
+}
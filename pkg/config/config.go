@@ -2,8 +2,10 @@ package config
 
 import (
 	"context"
+	"os"
 
 	"github.com/heetch/confita"
+	"github.com/heetch/confita/backend"
 	"github.com/heetch/confita/backend/env"
 	"github.com/pkg/errors"
 )
@@ -24,3 +26,21 @@ func Parse(cfg interface{}) error {
 	}
 	return nil
 }
+
+// ParseWithPrefix is same as Parse, but prefix is prepended to each key from
+// struct tags. It allows to parse same struct for several entities:
+// with prefix "FIRST_" tag `config:"VALUE"` is looked up in env var FIRST_VALUE.
+func ParseWithPrefix(prefix string, cfg interface{}) error {
+	prefixed := backend.Func("env", func(ctx context.Context, key string) ([]byte, error) {
+		if val := os.Getenv(prefix + key); val != "" {
+			return []byte(val), nil
+		}
+		return nil, backend.ErrNotFound
+	})
+
+	err := confita.NewLoader(prefixed).Load(context.Background(), cfg)
+	if err != nil {
+		return errors.Wrapf(err, "load env vars with prefix %q", prefix)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package schedule
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// maxCronIterations limits search of cron expression's next match,
+// it's enough to find match in several years.
+const maxCronIterations = 100000
+
+// Cron is schedule planning runs with classic 5-fields cron expression:
+// "minute hour day-of-month month day-of-week". Fields support numbers, "*",
+// lists ("1,15"), ranges ("1-5") and steps ("*/15", "0-30/10"). Day of week is 0-6
+// (sunday is 0 or 7). If both day of month and day of week are restricted, run is
+// planned when either matches (as in classic cron).
+type Cron struct {
+	minutes, hours, days, months, weekdays []bool
+	// isDayRestricted and isWeekdayRestricted are false if field is "*".
+	isDayRestricted, isWeekdayRestricted bool
+	loc                                  *time.Location
+}
+
+var _ Schedule = &Cron{}
+
+// ParseCron parses cron expression, runs are planned in loc's timezone.
+func ParseCron(expr string, loc *time.Location) (*Cron, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, errors.Errorf("cron expression %q must have 5 fields", expr)
+	}
+
+	var c Cron
+	var err error
+
+	c.minutes, err = parseCronField(fields[0], 0, 59)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse minutes")
+	}
+
+	c.hours, err = parseCronField(fields[1], 0, 23)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse hours")
+	}
+
+	c.days, err = parseCronField(fields[2], 1, 31)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse days of month")
+	}
+
+	c.months, err = parseCronField(fields[3], 1, 12)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse months")
+	}
+
+	c.weekdays, err = parseCronField(fields[4], 0, 7)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse days of week")
+	}
+	c.weekdays[0] = c.weekdays[0] || c.weekdays[7]
+
+	c.isDayRestricted = fields[2] != "*"
+	c.isWeekdayRestricted = fields[4] != "*"
+	c.loc = loc
+
+	if c.Next(time.Now()).IsZero() {
+		return nil, errors.Errorf("cron expression %q never matches", expr)
+	}
+
+	return &c, nil
+}
+
+// Next implements Schedule interface. Zero time returned if expression never matches.
+func (c *Cron) Next(now time.Time) time.Time {
+	t := now.In(c.loc).Truncate(time.Minute)
+	if t.Before(now) {
+		t = t.Add(time.Minute)
+	}
+
+	for i := 0; i < maxCronIterations; i++ {
+		switch {
+		case !c.months[t.Month()]:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, c.loc)
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, c.loc)
+		case !c.hours[t.Hour()]:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, c.loc)
+		case !c.minutes[t.Minute()]:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// dayMatches returns true if t's day matches day of month and day of week fields.
+func (c *Cron) dayMatches(t time.Time) bool {
+	day, weekday := c.days[t.Day()], c.weekdays[t.Weekday()]
+	if c.isDayRestricted && c.isWeekdayRestricted {
+		return day || weekday
+	}
+
+	return day && weekday
+}
+
+// parseCronField parses cron expression's field with values from min to max.
+// Returned slice is indexed by value and has length max+1.
+func parseCronField(field string, min, max int) ([]bool, error) {
+	values := make([]bool, max+1)
+
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.Index(part, "/"); i != -1 {
+			var err error
+			rng = part[:i]
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return nil, errors.Errorf("invalid step in %q", part)
+			}
+		}
+
+		from, to := min, max
+		if rng != "*" {
+			bounds := strings.SplitN(rng, "-", 2)
+
+			var err error
+			from, err = strconv.Atoi(bounds[0])
+			if err != nil {
+				return nil, errors.Errorf("invalid value in %q", part)
+			}
+
+			to = from
+			if len(bounds) == 2 {
+				to, err = strconv.Atoi(bounds[1])
+				if err != nil {
+					return nil, errors.Errorf("invalid value in %q", part)
+				}
+			} else if step != 1 {
+				// "5/15" means "from 5 to max with step 15".
+				to = max
+			}
+		}
+
+		if from < min || to > max || from > to {
+			return nil, errors.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+
+		for v := from; v <= to; v += step {
+			values[v] = true
+		}
+	}
+
+	return values, nil
+}
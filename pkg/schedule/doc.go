@@ -0,0 +1,3 @@
+// Package schedule provides schedules which plan times of periodic jobs:
+// fixed rate, cron expressions, random jitter and quiet hours
+package schedule
@@ -0,0 +1,108 @@
+package schedule
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// maxQuietIterations limits count of skipped runs planned in quiet hours,
+// it protects from endless loop with schedule which plans runs only in quiet hours.
+const maxQuietIterations = 1000
+
+// Quiet is schedule which skips runs of other schedule planned in quiet hours.
+// Runs are planned again by other schedule starting from end of quiet hours.
+type Quiet struct {
+	schedule Schedule
+	// start and end are minutes since midnight, end can be less than start if
+	// quiet hours continue over midnight.
+	start, end int
+	loc        *time.Location
+}
+
+var _ Schedule = &Quiet{}
+
+// WithQuietHours returns schedule which skips runs of schedule planned in quiet hours.
+// Quiet hours are formatted as "HH:MM-HH:MM" (for example "22:00-07:00") in loc's timezone.
+func WithQuietHours(schedule Schedule, hours string, loc *time.Location) (*Quiet, error) {
+	parts := strings.Split(hours, "-")
+	if len(parts) != 2 {
+		return nil, errors.Errorf("quiet hours %q are not in format HH:MM-HH:MM", hours)
+	}
+
+	start, err := parseClock(parts[0])
+	if err != nil {
+		return nil, errors.Wrap(err, "parse start of quiet hours")
+	}
+
+	end, err := parseClock(parts[1])
+	if err != nil {
+		return nil, errors.Wrap(err, "parse end of quiet hours")
+	}
+
+	if start == end {
+		return nil, errors.Errorf("quiet hours %q are empty", hours)
+	}
+
+	return &Quiet{
+		schedule: schedule,
+		start:    start,
+		end:      end,
+		loc:      loc,
+	}, nil
+}
+
+// Next implements Schedule interface.
+func (q *Quiet) Next(now time.Time) time.Time {
+	next := q.schedule.Next(now)
+	for i := 0; i < maxQuietIterations && q.isQuiet(next); i++ {
+		next = q.schedule.Next(q.quietEnd(next))
+	}
+
+	return next
+}
+
+// isQuiet returns true if t is in quiet hours.
+func (q *Quiet) isQuiet(t time.Time) bool {
+	t = t.In(q.loc)
+	minute := t.Hour()*60 + t.Minute()
+
+	if q.start < q.end {
+		return minute >= q.start && minute < q.end
+	}
+
+	return minute >= q.start || minute < q.end
+}
+
+// quietEnd returns end of quiet hours which t is in.
+func (q *Quiet) quietEnd(t time.Time) time.Time {
+	t = t.In(q.loc)
+	end := time.Date(t.Year(), t.Month(), t.Day(), q.end/60, q.end%60, 0, 0, q.loc)
+	if !end.After(t) {
+		end = end.AddDate(0, 0, 1)
+	}
+
+	return end
+}
+
+// parseClock parses time of day formatted as "HH:MM" to minutes since midnight.
+func parseClock(clock string) (int, error) {
+	parts := strings.Split(strings.TrimSpace(clock), ":")
+	if len(parts) != 2 {
+		return 0, errors.Errorf("time %q is not in format HH:MM", clock)
+	}
+
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil || hour < 0 || hour > 23 {
+		return 0, errors.Errorf("invalid hour in %q", clock)
+	}
+
+	minute, err := strconv.Atoi(parts[1])
+	if err != nil || minute < 0 || minute > 59 {
+		return 0, errors.Errorf("invalid minute in %q", clock)
+	}
+
+	return hour*60 + minute, nil
+}
@@ -0,0 +1,75 @@
+package schedule
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Schedule plans times of job's runs.
+type Schedule interface {
+	// Next returns time of next run which is not before now.
+	// Schedules can be stateful, so they must not be used concurrently.
+	Next(now time.Time) time.Time
+}
+
+// FixedRate is schedule with fixed period between starts of runs (not between end of
+// one run and start of next one), so runs don't drift by run's duration.
+// First run is planned immediately. Missed runs (if run took longer than period) are skipped.
+type FixedRate struct {
+	interval time.Duration
+	last     time.Time
+}
+
+var _ Schedule = &FixedRate{}
+
+// Every returns fixed rate schedule with specified interval.
+func Every(interval time.Duration) *FixedRate {
+	return &FixedRate{
+		interval: interval,
+	}
+}
+
+// Next implements Schedule interface.
+func (f *FixedRate) Next(now time.Time) time.Time {
+	if f.last.IsZero() {
+		f.last = now
+		return now
+	}
+
+	next := f.last.Add(f.interval)
+	if next.Before(now) {
+		missed := (now.Sub(next) + f.interval - 1) / f.interval
+		next = next.Add(missed * f.interval)
+	}
+
+	f.last = next
+
+	return next
+}
+
+// Jitter is schedule which delays runs of other schedule by random duration,
+// so replicas with same schedule don't run jobs simultaneously.
+type Jitter struct {
+	schedule Schedule
+	max      time.Duration
+}
+
+var _ Schedule = &Jitter{}
+
+// WithJitter returns schedule which delays runs of schedule by random duration up to max.
+func WithJitter(schedule Schedule, max time.Duration) *Jitter {
+	return &Jitter{
+		schedule: schedule,
+		max:      max,
+	}
+}
+
+// Next implements Schedule interface.
+func (j *Jitter) Next(now time.Time) time.Time {
+	next := j.schedule.Next(now)
+	if j.max <= 0 {
+		return next
+	}
+
+	return next.Add(time.Duration(rand.Int63n(int64(j.max)))) //nolint:gosec // Jitter doesn't need crypto rand.
+}